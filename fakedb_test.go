@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/moderation"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const testSecret = "test-secret"
+
+/**
+ * Result of one query of fake database: rows for queries, affected rows for exec
+ */
+type fakeResult struct {
+	Rows     [][]driver.Value
+	Affected int64
+	Err      error
+}
+
+/**
+ * Answers queries of internal/database by the sqlc name of the query, so handlers run without Postgres.
+ * Query without answer fails, so a handler which touches something unexpected gets an error
+ */
+type fakeDB struct {
+	mu      sync.Mutex
+	answers map[string]func(args []driver.Value) fakeResult
+	runs    map[string]int
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		answers: map[string]func(args []driver.Value) fakeResult{},
+		runs:    map[string]int{},
+	}
+}
+
+/**
+ * Answer query with the same result every time
+ */
+func (db *fakeDB) on(name string, result fakeResult) {
+	db.onArgs(name, func([]driver.Value) fakeResult { return result })
+}
+
+/**
+ * Answer query depending on its arguments, e.g. rows only for one user
+ */
+func (db *fakeDB) onArgs(name string, answer func(args []driver.Value) fakeResult) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.answers[name] = answer
+}
+
+/**
+ * Number of times the query was run
+ */
+func (db *fakeDB) ran(name string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.runs[name]
+}
+
+func (db *fakeDB) query(query string, args []driver.NamedValue) fakeResult {
+	name := query
+	if rest, ok := strings.CutPrefix(query, "-- name: "); ok {
+		name, _, _ = strings.Cut(rest, " ")
+	}
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	db.mu.Lock()
+	db.runs[name]++
+	answer, ok := db.answers[name]
+	db.mu.Unlock()
+	if !ok {
+		return fakeResult{Err: fmt.Errorf("unexpected query %s", name)}
+	}
+	return answer(values)
+}
+
+/**
+ * Check if the value is one of the query arguments, uuids are passed as strings
+ */
+func hasArg(args []driver.Value, value any) bool {
+	for _, arg := range args {
+		if fmt.Sprint(arg) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Rows of models in column order, models are structs of internal/database
+ */
+func rows(models ...any) fakeResult {
+	result := fakeResult{Rows: make([][]driver.Value, len(models))}
+	for i, model := range models {
+		result.Rows[i] = row(reflect.ValueOf(model))
+	}
+	return result
+}
+
+func row(model reflect.Value) []driver.Value {
+	var values []driver.Value
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		switch v := field.Interface().(type) {
+		case nil:
+			values = append(values, nil)
+		case driver.Valuer:
+			value, _ := v.Value()
+			values = append(values, value)
+		case time.Time, string, bool, float64, []byte:
+			values = append(values, v)
+		default:
+			switch field.Kind() {
+			case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+				values = append(values, field.Int())
+			case reflect.Slice:
+				value, _ := pq.Array(v).Value()
+				values = append(values, value)
+			case reflect.Struct:
+				// Embedded model of sqlc.embed
+				values = append(values, row(field)...)
+			}
+		}
+	}
+	return values
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (conn fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare is not supported")
+}
+func (conn fakeConn) Close() error              { return nil }
+func (conn fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (conn fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := conn.db.query(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &fakeRows{rows: result.Rows}, nil
+}
+
+func (conn fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := conn.db.query(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.Affected), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (rows *fakeRows) Columns() []string {
+	if len(rows.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(rows.rows[0]))
+}
+
+func (rows *fakeRows) Close() error { return nil }
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if rows.next == len(rows.rows) {
+		return io.EOF
+	}
+	copy(dest, rows.rows[rows.next])
+	rows.next++
+	return nil
+}
+
+/**
+ * Config of the server with fake database. Moderation has no filters, so text is allowed as it is
+ */
+func newTestConfig(t *testing.T, db *fakeDB) *apiConfig {
+	t.Helper()
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+	return &apiConfig{
+		db:          database.New(conn),
+		conn:        conn,
+		tokenSecret: testSecret,
+		moderator:   moderation.Chain{},
+	}
+}
+
+/**
+ * Access token of the user signed with the test secret
+ */
+func testToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, testSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+/**
+ * Serve one request with the route pattern, so path values are set like in main
+ */
+func serveTest(pattern string, handler http.HandlerFunc, target, token, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+
+	method, _, _ := strings.Cut(pattern, " ")
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+/**
+ * Decode json response, test fails on other status
+ */
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/activitypub"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/google/uuid"
+)
+
+// Time for delivery of one activity to one inbox
+const deliveryTimeout = 15 * time.Second
+
+var errInvalidActivity = errors.New("invalid activity")
+
+func actorURI(base string, userID uuid.UUID) string {
+	return base + "/ap/users/" + userID.String()
+}
+
+func noteURI(base string, chirpID uuid.UUID) string {
+	return base + "/ap/chirps/" + chirpID.String()
+}
+
+/**
+ * Get id from URI of a local object, e.g. chirp id from note URI. ok is false for URIs of other servers
+ */
+func localID(base, prefix, uri string) (uuid.UUID, bool) {
+	rest, found := strings.CutPrefix(uri, base+prefix)
+	if !found {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(rest)
+	return id, err == nil
+}
+
+/**
+ * Get key pair of local actor, it is generated when the actor needs it for the first time
+ */
+func (cfg *apiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := cfg.db.GetActorKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	// Concurrent request may have created the key, the first one is kept
+	err = cfg.db.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	return cfg.db.GetActorKey(ctx, userID)
+}
+
+func toActor(base string, user database.User, key database.ActorKey) activitypub.Actor {
+	id := actorURI(base, user.ID)
+	name := user.DisplayName
+	if name == "" {
+		name = user.Handle.String
+	}
+	actor := activitypub.Actor{
+		Context:           []string{activitypub.ActivityStreams, activitypub.Security},
+		ID:                id,
+		Type:              activitypub.TypePerson,
+		PreferredUsername: user.Handle.String,
+		Name:              name,
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		URL:               base + "/api/chirps?author_id=" + user.ID.String(),
+		PublicKey: activitypub.PublicKey{
+			ID:           id + "#main-key",
+			Owner:        id,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	}
+	if user.Bio != "" {
+		actor.Summary = activitypub.HTMLContent(user.Bio)
+	}
+	return actor
+}
+
+/**
+ * Chirp as a public note, replies to local chirps are linked with inReplyTo
+ */
+func toNote(base string, chirp database.Chirp) activitypub.Note {
+	content := chirp.Body
+	if chirp.QuoteOf.Valid {
+		content += "\n\nRE: " + noteURI(base, chirp.QuoteOf.UUID)
+	}
+
+	note := activitypub.Note{
+		ID:           noteURI(base, chirp.ID),
+		Type:         activitypub.TypeNote,
+		AttributedTo: actorURI(base, chirp.UserID),
+		Content:      activitypub.HTMLContent(content),
+		URL:          base + "/api/chirps/" + chirp.ID.String(),
+		Published:    chirp.CreatedAt.UTC().Format(time.RFC3339),
+		To:           []string{activitypub.Public},
+		Cc:           []string{actorURI(base, chirp.UserID) + "/followers"},
+	}
+	if chirp.UpdatedAt.After(chirp.CreatedAt) {
+		note.Updated = chirp.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if chirp.InReplyTo.Valid {
+		note.InReplyTo = noteURI(base, chirp.InReplyTo.UUID)
+	}
+	return note
+}
+
+/**
+ * Only public chirps with own body are federated, rechirps and other visibilities stay local
+ */
+func isFederated(chirp database.Chirp) bool {
+	return chirp.Visibility == visibilityPublic && !chirp.RechirpOf.Valid
+}
+
+func createActivity(base string, chirp database.Chirp) (activitypub.Activity, error) {
+	note := toNote(base, chirp)
+	object, err := json.Marshal(note)
+	if err != nil {
+		return activitypub.Activity{}, err
+	}
+	return activitypub.Activity{
+		Context:   activitypub.ActivityStreams,
+		ID:        note.ID + "/activity",
+		Type:      activitypub.TypeCreate,
+		Actor:     note.AttributedTo,
+		Object:    object,
+		To:        note.To,
+		Cc:        note.Cc,
+		Published: note.Published,
+	}, nil
+}
+
+/**
+ * Subscribe delivery of chirps to remote followers to domain events
+ */
+func (cfg *apiConfig) subscribeFederation(bus *events.Bus) {
+	bus.Subscribe(events.ChirpPublished, cfg.federateChirpPublished)
+	bus.Subscribe(events.ChirpDeleted, cfg.federateChirpDeleted)
+}
+
+func (cfg *apiConfig) federateChirpPublished(ctx context.Context, event events.Event) error {
+	// URIs of objects must be stable, federation works only with PUBLIC_URL
+	if cfg.federation == nil || cfg.publicURL == "" {
+		return nil
+	}
+
+	chirp, err := cfg.db.GetChirpByID(ctx, event.ChirpID)
+	if err != nil {
+		return err
+	}
+	if !isFederated(chirp) {
+		return nil
+	}
+
+	activity, err := createActivity(cfg.publicURL, chirp)
+	if err != nil {
+		return err
+	}
+	return cfg.deliverToFollowers(ctx, chirp.UserID, activity)
+}
+
+func (cfg *apiConfig) federateChirpDeleted(ctx context.Context, event events.Event) error {
+	if cfg.federation == nil || cfg.publicURL == "" {
+		return nil
+	}
+
+	chirp, err := cfg.db.GetChirpByID(ctx, event.ChirpID)
+	if err != nil {
+		return err
+	}
+	if !isFederated(chirp) {
+		return nil
+	}
+
+	id := noteURI(cfg.publicURL, chirp.ID)
+	object, err := json.Marshal(map[string]string{"id": id, "type": activitypub.TypeTombstone})
+	if err != nil {
+		return err
+	}
+	return cfg.deliverToFollowers(ctx, chirp.UserID, activitypub.Activity{
+		Context: activitypub.ActivityStreams,
+		ID:      id + "/delete",
+		Type:    activitypub.TypeDelete,
+		Actor:   actorURI(cfg.publicURL, chirp.UserID),
+		Object:  object,
+		To:      []string{activitypub.Public},
+	})
+}
+
+/**
+ * Deliver activity of the user to inboxes of remote followers. Delivery runs in background,
+ * so slow servers don't slow down the request, failures are logged
+ */
+func (cfg *apiConfig) deliverToFollowers(ctx context.Context, userID uuid.UUID, activity activitypub.Activity) error {
+	inboxes, err := cfg.db.GetRemoteFollowerInboxes(ctx, userID)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+
+	go func() {
+		for _, inbox := range inboxes {
+			if err := cfg.deliver(context.Background(), userID, inbox, activity); err != nil {
+				log.Printf("deliver %s: %v", activity.ID, err)
+			}
+		}
+	}()
+	return nil
+}
+
+/**
+ * Deliver activity to one inbox, signed with the key of the user
+ */
+func (cfg *apiConfig) deliver(ctx context.Context, userID uuid.UUID, inbox string, activity interface{}) error {
+	key, err := cfg.actorKey(ctx, userID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	return cfg.federation.Deliver(ctx, inbox, activity, actorURI(cfg.publicURL, userID)+"#main-key", privateKey)
+}
+
+/**
+ * Fetch actor of other server and save it. Actor document must be served from the host of its id,
+ * so one server can't pretend to be an actor of another one
+ */
+func (cfg *apiConfig) fetchRemoteActor(ctx context.Context, uri string) (database.RemoteActor, error) {
+	var actor activitypub.Actor
+	if err := cfg.federation.Fetch(ctx, uri, &actor); err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	fetched, err := url.Parse(uri)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	id, err := url.Parse(actor.ID)
+	if err != nil || id.Host != fetched.Host || actor.Inbox == "" || actor.PublicKey.Owner != actor.ID {
+		return database.RemoteActor{}, errors.New("invalid actor document " + uri)
+	}
+
+	return cfg.db.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		Uri:               actor.ID,
+		KeyID:             actor.PublicKey.ID,
+		Inbox:             actor.Inbox,
+		PreferredUsername: actor.PreferredUsername,
+		PublicKeyPem:      actor.PublicKey.PublicKeyPem,
+	})
+}
@@ -2,7 +2,13 @@ module github.com/St5/goboot-srv
 
 go 1.23.2
 
-require golang.org/x/crypto v0.30.0
+require (
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/crypto v0.30.0
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.32.0
+	golang.org/x/text v0.21.0
+)
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/mail"
+)
+
+// Purpose of tokens sent by email
+const (
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+)
+
+// How long tokens sent by email are valid
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+)
+
+// Time for sending one email in background
+const mailTimeout = time.Minute
+
+var (
+	errInvalidAccountToken = errors.New("invalid or expired token")
+	// Links in emails are built only from PUBLIC_URL, Host header of a request can be forged
+	errMailNotConfigured = errors.New("PUBLIC_URL is not set, emails with tokens are not sent")
+)
+
+/**
+ * Create mailer from env: MAIL_BACKEND=smtp sends to SMTP_ADDR, file saves emails to MAIL_DIR,
+ * otherwise emails are written to the log
+ */
+func newMailer() mail.Mailer {
+	switch os.Getenv("MAIL_BACKEND") {
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			addr = "localhost:1025"
+		}
+		return mail.SMTPMailer{
+			Addr:     addr,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		return mail.FileMailer{Dir: dir}
+	}
+	return mail.LogMailer{}
+}
+
+/**
+ * Send email in background, so response time doesn't show if the email was sent. Failures are logged
+ */
+func (cfg *apiConfig) sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := cfg.mailer.Send(ctx, cfg.mailFrom, msg); err != nil {
+			log.Printf("send mail to %s: %v", msg.To, err)
+		}
+	}()
+}
+
+/**
+ * Create single-use token for the user, earlier tokens with the same purpose stop working
+ */
+func (cfg *apiConfig) createAccountToken(ctx context.Context, user database.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.inTx(ctx, func(q *database.Queries) error {
+		err := q.InvalidateAccountTokens(ctx, database.InvalidateAccountTokensParams{UserID: user.ID, Purpose: purpose})
+		if err != nil {
+			return err
+		}
+		return q.CreateAccountToken(ctx, database.CreateAccountTokenParams{
+			TokenHash: auth.HashToken(token),
+			UserID:    user.ID,
+			Purpose:   purpose,
+			Email:     user.Email,
+			ExpiresAt: time.Now().UTC().Add(ttl),
+		})
+	})
+	return token, err
+}
+
+/**
+ * Send token for verification of the current email of the user
+ */
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	if cfg.publicURL == "" {
+		return errMailNotConfigured
+	}
+	token, err := cfg.createAccountToken(ctx, user, purposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email",
+		Body: fmt.Sprintf("Welcome to Chirpy!\n\nYour verification token: %s\n\n"+
+			"Send it to %s/api/email/verify to confirm this email. The token expires in 24 hours.\n",
+			token, cfg.publicURL),
+	})
+	return nil
+}
+
+/**
+ * Handle forgotten password, body is {"email": "..."}. Response is the same for unknown emails,
+ * so it doesn't show who has an account
+ */
+func (cfg *apiConfig) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// Checked before the lookup, so the response doesn't depend on the account
+	if cfg.publicURL == "" {
+		respondWithError(w, http.StatusServiceUnavailable, "Password reset is not configured")
+		return
+	}
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), strings.TrimSpace(params.Email))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusAccepted, nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	token, err := cfg.createAccountToken(r.Context(), user, purposePasswordReset, passwordResetTTL)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	cfg.sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Chirpy account.\n\nYour reset token: %s\n\n"+
+			"Send it with a new password to %s/api/password/reset. The token expires in 1 hour and works once.\n"+
+			"If you didn't ask for it, ignore this email.\n",
+			token, cfg.publicURL),
+	})
+
+	respondWithJSON(w, http.StatusAccepted, nil)
+}
+
+/**
+ * Handle reset password with token from email, body is {"token": "...", "password": "..."}.
+ * All refresh tokens of the user are revoked, so other sessions have to login again
+ */
+func (cfg *apiConfig) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Password is required")
+		return
+	}
+
+	hashed, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		token, err := q.ConsumeAccountToken(r.Context(), database.ConsumeAccountTokenParams{
+			TokenHash: auth.HashToken(params.Token),
+			Purpose:   purposePasswordReset,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return errInvalidAccountToken
+		}
+		if err != nil {
+			return err
+		}
+
+		// Token was sent to the old email when the email has changed since
+		user, err := q.GetUserByID(r.Context(), token.UserID)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(user.Email, token.Email) {
+			return errInvalidAccountToken
+		}
+
+		err = q.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{HashedPassword: hashed, ID: user.ID})
+		if err != nil {
+			return err
+		}
+		err = q.RevokeUserRefreshTokens(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		// The user got the token, so the email is theirs
+		_, err = q.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{ID: user.ID, Email: token.Email})
+		return err
+	})
+	if errors.Is(err, errInvalidAccountToken) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle send of new verification email to the authenticated user
+ */
+func (cfg *apiConfig) handleRequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email is already verified")
+		return
+	}
+
+	err = cfg.sendVerificationEmail(r.Context(), user)
+	if errors.Is(err, errMailNotConfigured) {
+		respondWithError(w, http.StatusServiceUnavailable, "Email verification is not configured")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, nil)
+}
+
+/**
+ * Handle verify email with token from email, body is {"token": "..."}
+ */
+func (cfg *apiConfig) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	token, err := cfg.db.ConsumeAccountToken(r.Context(), database.ConsumeAccountTokenParams{
+		TokenHash: auth.HashToken(params.Token),
+		Purpose:   purposeEmailVerification,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	// Nothing is verified when the email has changed after the token was sent
+	verified, err := cfg.db.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{ID: token.UserID, Email: token.Email})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if verified == 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+/**
+ * Answer ConsumeAccountToken like the query: the token is found once, later it is used
+ */
+func onAccountToken(db *fakeDB, token string, accountToken database.AccountToken) {
+	var once sync.Once
+	db.onArgs("ConsumeAccountToken", func(args []driver.Value) fakeResult {
+		result := fakeResult{}
+		if hasArg(args, auth.HashToken(token)) && hasArg(args, accountToken.Purpose) {
+			once.Do(func() { result = rows(accountToken) })
+		}
+		return result
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	user := database.User{ID: uuid.New(), Email: "user@example.com"}
+	resetToken := database.AccountToken{UserID: user.ID, Purpose: purposePasswordReset, Email: user.Email, ExpiresAt: time.Now().Add(time.Hour)}
+	changedEmail := resetToken
+	changedEmail.Email = "old@example.com"
+
+	tests := []struct {
+		name         string
+		accountToken database.AccountToken
+		bodies       []string
+		want         []int
+		wantReset    bool
+	}{
+		{name: "reset", accountToken: resetToken,
+			bodies: []string{`{"token": "secret", "password": "new"}`}, want: []int{http.StatusNoContent}, wantReset: true},
+		{name: "token used twice", accountToken: resetToken,
+			bodies: []string{`{"token": "secret", "password": "new"}`, `{"token": "secret", "password": "other"}`},
+			want:   []int{http.StatusNoContent, http.StatusBadRequest}, wantReset: true},
+		{name: "unknown token", accountToken: resetToken,
+			bodies: []string{`{"token": "guess", "password": "new"}`}, want: []int{http.StatusBadRequest}},
+		{name: "missing password", accountToken: resetToken,
+			bodies: []string{`{"token": "secret"}`}, want: []int{http.StatusBadRequest}},
+		// Token was sent before the email of the user has changed
+		{name: "email changed", accountToken: changedEmail,
+			bodies: []string{`{"token": "secret", "password": "new"}`}, want: []int{http.StatusBadRequest}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			onAccountToken(db, "secret", tt.accountToken)
+			db.on("GetUserByID", rows(user))
+			db.on("UpdateUserPassword", fakeResult{Affected: 1})
+			db.on("RevokeUserRefreshTokens", fakeResult{Affected: 2})
+			db.on("MarkEmailVerified", fakeResult{Affected: 1})
+			cfg := newTestConfig(t, db)
+
+			for i, body := range tt.bodies {
+				rec := serveTest("POST /api/password/reset", cfg.handleResetPassword, "/api/password/reset", "", body)
+				if rec.Code != tt.want[i] {
+					t.Fatalf("request %d: status = %d, want %d: %s", i+1, rec.Code, tt.want[i], rec.Body)
+				}
+			}
+
+			// Password changes once and every session of the user has to login again
+			wantRun := 0
+			if tt.wantReset {
+				wantRun = 1
+			}
+			if got := db.ran("UpdateUserPassword"); got != wantRun {
+				t.Errorf("password updated %d times, want %d", got, wantRun)
+			}
+			if got := db.ran("RevokeUserRefreshTokens"); got != wantRun {
+				t.Errorf("sessions revoked %d times, want %d", got, wantRun)
+			}
+		})
+	}
+}
+
+func TestVerifyEmailTokenWorksOnce(t *testing.T) {
+	userID := uuid.New()
+	db := newFakeDB()
+	onAccountToken(db, "secret", database.AccountToken{UserID: userID, Purpose: purposeEmailVerification, Email: "user@example.com"})
+	db.on("MarkEmailVerified", fakeResult{Affected: 1})
+	cfg := newTestConfig(t, db)
+
+	for i, want := range []int{http.StatusNoContent, http.StatusBadRequest} {
+		rec := serveTest("POST /api/email/verify", cfg.handleVerifyEmail, "/api/email/verify", "", `{"token": "secret"}`)
+		if rec.Code != want {
+			t.Fatalf("request %d: status = %d, want %d: %s", i+1, rec.Code, want, rec.Body)
+		}
+	}
+	if got := db.ran("MarkEmailVerified"); got != 1 {
+		t.Errorf("email verified %d times, want 1", got)
+	}
+}
+
+func TestPasswordResetTokenIsNotEmailVerification(t *testing.T) {
+	db := newFakeDB()
+	onAccountToken(db, "secret", database.AccountToken{UserID: uuid.New(), Purpose: purposePasswordReset, Email: "user@example.com"})
+	cfg := newTestConfig(t, db)
+
+	rec := serveTest("POST /api/email/verify", cfg.handleVerifyEmail, "/api/email/verify", "", `{"token": "secret"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/St5/goboot-srv/internal/auth"
 )
 
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
 
+	cfg.db.ResetAllChirps(r.Context())
 	cfg.db.ResetAllUsers(r.Context())
 	cfg.fileserverHits.Store(0)
 	w.WriteHeader(200)
@@ -35,3 +38,16 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+/**
+ * Allow request only with admin key in header Authorization: ApiKey <ADMIN_KEY>
+ */
+func (cfg *apiConfig) middlewareAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey, err := auth.GetAPIKey(r.Header)
+		if err != nil || cfg.adminKey == "" || apiKey != cfg.adminKey {
+			respondWithError(w, 401, "Unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
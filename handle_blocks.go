@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+/**
+ * Get authenticated user and other user from path for block and mute handlers.
+ * Error response is already written when ok is false
+ */
+func (cfg *apiConfig) relationUsers(w http.ResponseWriter, r *http.Request) (userID uuid.UUID, otherID uuid.UUID, ok bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err = auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	otherID, err = uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid userID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if otherID == userID {
+		respondWithError(w, 400, "You can`t do it with yourself")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, otherID, true
+}
+
+/**
+ * Handle block of user. Blocked user can't see chirps of the blocker, reply, mention, react or follow.
+ * Follows in both directions are removed
+ */
+func (cfg *apiConfig) handleBlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, blockedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := cfg.db.GetUserByID(r.Context(), blockedID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.BlockUser(r.Context(), database.BlockUserParams{
+			BlockerID: userID,
+			BlockedID: blockedID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+			FollowerID: userID,
+			FolloweeID: blockedID,
+		})
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle unblock of user, removed follows are not restored
+ */
+func (cfg *apiConfig) handleUnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, blockedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle mute of user. Chirps of muted user are filtered from lists and timeline of the user,
+ * muted user doesn't know about it
+ */
+func (cfg *apiConfig) handleMuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, mutedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := cfg.db.GetUserByID(r.Context(), mutedID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	err = cfg.db.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle unmute of user
+ */
+func (cfg *apiConfig) handleUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, mutedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestRelationNeedsOtherUser(t *testing.T) {
+	userID := uuid.New()
+	handlers := []struct {
+		pattern string
+		action  string
+		handler func(*apiConfig, http.ResponseWriter, *http.Request)
+	}{
+		{"POST /api/users/{userID}/block", "block", (*apiConfig).handleBlockUser},
+		{"DELETE /api/users/{userID}/block", "block", (*apiConfig).handleUnblockUser},
+		{"POST /api/users/{userID}/mute", "mute", (*apiConfig).handleMuteUser},
+		{"DELETE /api/users/{userID}/mute", "mute", (*apiConfig).handleUnmuteUser},
+	}
+
+	for _, h := range handlers {
+		for _, otherID := range []string{"abc", userID.String()} {
+			t.Run(h.pattern+" "+otherID, func(t *testing.T) {
+				cfg := newTestConfig(t, newFakeDB())
+				handler := func(w http.ResponseWriter, r *http.Request) { h.handler(cfg, w, r) }
+
+				rec := serveTest(h.pattern, handler, "/api/users/"+otherID+"/"+h.action, testToken(t, userID), "")
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestBlockUser(t *testing.T) {
+	userID, blockedID := uuid.New(), uuid.New()
+	target := "/api/users/" + blockedID.String() + "/block"
+
+	tests := []struct {
+		name  string
+		found bool
+		want  int
+	}{
+		{name: "unknown user", want: http.StatusNotFound},
+		{name: "user", found: true, want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.found {
+				db.on("GetUserByID", rows(database.User{ID: blockedID}))
+			} else {
+				db.on("GetUserByID", fakeResult{})
+			}
+			db.on("BlockUser", fakeResult{Affected: 1})
+			db.on("DeleteFollowsBetween", fakeResult{Affected: 1})
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/users/{userID}/block", cfg.handleBlockUser, target, testToken(t, userID), "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			// Follows go away together with the block
+			wantRun := 0
+			if tt.found {
+				wantRun = 1
+			}
+			if db.ran("BlockUser") != wantRun || db.ran("DeleteFollowsBetween") != wantRun {
+				t.Errorf("block ran %d times, follows deleted %d times, want %d",
+					db.ran("BlockUser"), db.ran("DeleteFollowsBetween"), wantRun)
+			}
+		})
+	}
+}
+
+func TestMuteUnknownUser(t *testing.T) {
+	db := newFakeDB()
+	db.on("GetUserByID", fakeResult{})
+	cfg := newTestConfig(t, db)
+
+	rec := serveTest("POST /api/users/{userID}/mute", cfg.handleMuteUser, "/api/users/"+uuid.NewString()+"/mute", testToken(t, uuid.New()), "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+	}
+	if db.ran("MuteUser") != 0 {
+		t.Error("unknown user was muted")
+	}
+}
+
+func TestBlockedViewerCantGetChirp(t *testing.T) {
+	authorID, blockedID, chirpID := uuid.New(), uuid.New(), uuid.New()
+	chirp := database.Chirp{ID: chirpID, Body: "hello", UserID: authorID, Status: chirpPublished}
+
+	tests := []struct {
+		name     string
+		viewerID uuid.UUID
+		want     int
+	}{
+		{name: "blocked viewer", viewerID: blockedID, want: http.StatusNotFound},
+		{name: "other viewer", viewerID: uuid.New(), want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			onVisibleChirp(db, chirp, blockedID)
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("GET /api/chirps/{chirpID}", cfg.handleGetChirp, "/api/chirps/"+chirpID.String(), testToken(t, tt.viewerID), "")
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
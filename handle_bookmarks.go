@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+/**
+ * Handle bookmark of chirp. Bookmarks are private, only the user can see them
+ */
+func (confg *apiConfig) handleCreateBookmark(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil || !isPublic(chirp) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	err = confg.db.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+/**
+ * Handle remove bookmark of chirp
+ */
+func (confg *apiConfig) handleDeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	err = confg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+/**
+ * Handle list of bookmarks of authenticated user, latest bookmark first
+ */
+func (confg *apiConfig) handleGetBookmarks(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bookmarks, err := confg.db.GetBookmarkedChirps(r.Context(), database.GetBookmarkedChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if len(bookmarks) > limit {
+		bookmarks = bookmarks[:limit]
+		last := bookmarks[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.BookmarkedAt, ID: last.Chirp.ID})
+	}
+
+	chirpsResponse := make([]Chirpy, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		chirpsResponse[i] = toChirpy(bookmark.Chirp)
+	}
+
+	err = confg.enrichChirps(r, userID, chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
+
+/**
+ * Mark chirps bookmarked by the viewer
+ */
+func (confg *apiConfig) withBookmarks(ctx context.Context, viewerID uuid.UUID, chirps []*Chirpy) error {
+	if len(chirps) == 0 || viewerID == uuid.Nil {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	bookmarked, err := confg.db.GetViewerBookmarks(ctx, database.GetViewerBookmarksParams{
+		UserID:   viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	set := make(map[uuid.UUID]bool, len(bookmarked))
+	for _, id := range bookmarked {
+		set[id] = true
+	}
+	for _, chirp := range chirps {
+		chirp.Bookmarked = set[chirp.ID]
+	}
+
+	return nil
+}
@@ -1,29 +1,379 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/St5/goboot-srv/internal/auth"
 	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/moderation"
+	"github.com/St5/goboot-srv/internal/paging"
 	"github.com/google/uuid"
 )
 
 type Chirpy struct {
-	ID        uuid.UUID `json:"id"`
-	CreateAt  string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID            uuid.UUID        `json:"id"`
+	CreateAt      string           `json:"created_at"`
+	UpdatedAt     string           `json:"updated_at"`
+	Body          string           `json:"body"`
+	UserID        uuid.UUID        `json:"user_id"`
+	Edited        bool             `json:"edited"`
+	InReplyTo     *uuid.UUID       `json:"in_reply_to"`
+	ReplyCount    int32            `json:"reply_count"`
+	Deleted       bool             `json:"deleted,omitempty"`
+	Reactions     map[string]int64 `json:"reactions"`
+	ViewerReacted []string         `json:"viewer_reacted,omitempty"`
+	QuoteOf       *uuid.UUID       `json:"quote_of,omitempty"`
+	RechirpOf     *uuid.UUID       `json:"rechirp_of,omitempty"`
+	Referenced    *Chirpy          `json:"referenced_chirp,omitempty"`
+	Entities      []Entity         `json:"entities"`
+	Attachments   []Attachment     `json:"attachments"`
+	Status        string           `json:"status"`
+	Visibility    string           `json:"visibility"`
+	PublishAt     *time.Time       `json:"publish_at,omitempty"`
+	DeletedAt     *time.Time       `json:"deleted_at,omitempty"`
+	Poll          *Poll            `json:"poll,omitempty"`
+	Bookmarked    bool             `json:"bookmarked,omitempty"`
+	Pinned        bool             `json:"pinned,omitempty"`
+	Rank          float32          `json:"rank,omitempty"`
+	Snippet       string           `json:"snippet,omitempty"`
+	Author        *Author          `json:"author,omitempty"`
+}
+
+type ChirpThread struct {
+	Ancestors []Chirpy `json:"ancestors"`
+	Chirp     Chirpy   `json:"chirp"`
+	Replies   []Chirpy `json:"replies"`
+}
+
+// Max number of replies returned in a thread
+const maxThreadReplies = 500
+
+// Status of chirp: scheduled chirps are published by publisher at publish_at, drafts only by the owner
+const (
+	chirpPublished = "published"
+	chirpScheduled = "scheduled"
+	chirpDraft     = "draft"
+)
+
+// What chirp does with other chirp it references
+const (
+	targetReply   = "reply"
+	targetQuote   = "quote"
+	targetRechirp = "rechirp"
+)
+
+var (
+	errTargetNotFound     = errors.New("referenced chirp not found")
+	errTargetNotShareable = errors.New("referenced chirp can't be shared")
+)
+
+// Visibility of chirp: unlisted chirps are not in public lists, followers-only and mentioned-only chirps
+// are readable only by followers or mentioned users
+const (
+	visibilityPublic    = "public"
+	visibilityUnlisted  = "unlisted"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+)
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  string    `json:"created_at"`
+	ReplacedAt string    `json:"replaced_at"`
+}
+
+/**
+ * Convert database.Chirp to Chirpy model for json response with correct format fields
+ */
+func toChirpy(chirp database.Chirp) Chirpy {
+	//Body of deleted chirp is kept for restore, but it is not shown
+	body := chirp.Body
+	if chirp.DeletedAt.Valid {
+		body = ""
+	}
+
+	chirpy := Chirpy{
+		ID:          chirp.ID,
+		CreateAt:    chirp.CreatedAt.String(),
+		UpdatedAt:   chirp.UpdatedAt.String(),
+		Body:        body,
+		UserID:      chirp.UserID,
+		Edited:      chirp.UpdatedAt.After(chirp.CreatedAt) && !chirp.DeletedAt.Valid,
+		ReplyCount:  chirp.ReplyCount,
+		Deleted:     chirp.DeletedAt.Valid,
+		Reactions:   map[string]int64{},
+		Entities:    toEntities(body),
+		Attachments: []Attachment{},
+		Status:      chirp.Status,
+		Visibility:  chirp.Visibility,
+	}
+	if chirp.PublishAt.Valid {
+		chirpy.PublishAt = &chirp.PublishAt.Time
+	}
+	if chirp.InReplyTo.Valid {
+		chirpy.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	if chirp.QuoteOf.Valid {
+		chirpy.QuoteOf = &chirp.QuoteOf.UUID
+	}
+	if chirp.RechirpOf.Valid {
+		chirpy.RechirpOf = &chirp.RechirpOf.UUID
+	}
+	return chirpy
+}
+
+/**
+ * Chirp is shown to everyone and can be replied, quoted or reacted
+ */
+func isPublic(chirp database.Chirp) bool {
+	return chirp.Status == chirpPublished && !chirp.DeletedAt.Valid
+}
+
+/**
+ * Scheduled chirps and drafts are shown only to the owner
+ */
+func canView(chirp database.Chirp, viewerID uuid.UUID) bool {
+	return !chirp.DeletedAt.Valid && (chirp.Status == chirpPublished || chirp.UserID == viewerID)
+}
+
+/**
+ * Check if chirp can be quoted or rechirped, it would show followers-only and mentioned-only chirps to others
+ */
+func isShareable(chirp database.Chirp) bool {
+	return chirp.Visibility == visibilityPublic || chirp.Visibility == visibilityUnlisted
 }
 
 /**
- * Handle create chirp
+ * Load chirp which chirp of the user replies to, quotes or rechirps. The user must see it: it is published,
+ * not deleted and not hidden by block or visibility. Quoted and rechirped chirps must be shareable
+ */
+func (confg *apiConfig) loadTarget(ctx context.Context, userID, targetID uuid.UUID, kind string) (database.Chirp, error) {
+	target, err := confg.db.GetVisibleChirpByID(ctx, database.GetVisibleChirpByIDParams{
+		ID:       targetID,
+		ViewerID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Chirp{}, errTargetNotFound
+	}
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !isPublic(target) {
+		return database.Chirp{}, errTargetNotFound
+	}
+	if kind != targetReply && !isShareable(target) {
+		return database.Chirp{}, errTargetNotShareable
+	}
+	return target, nil
+}
+
+/**
+ * Check chirps referenced by draft or scheduled chirp when it is published, they could be deleted
+ * or hidden from the author since the chirp was saved. Message is empty when all of them are fine
+ */
+func (confg *apiConfig) checkTargets(ctx context.Context, chirp database.Chirp) (string, error) {
+	targets := []struct {
+		kind string
+		id   uuid.NullUUID
+	}{
+		{targetReply, chirp.InReplyTo},
+		{targetQuote, chirp.QuoteOf},
+		{targetRechirp, chirp.RechirpOf},
+	}
+	for _, target := range targets {
+		if !target.id.Valid {
+			continue
+		}
+		_, err := confg.loadTarget(ctx, chirp.UserID, target.id.UUID, target.kind)
+		if errors.Is(err, errTargetNotFound) || errors.Is(err, errTargetNotShareable) {
+			return targetMessage(target.kind, err), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+/**
+ * Message for the author why the target can't be referenced
+ */
+func targetMessage(kind string, err error) string {
+	if !errors.Is(err, errTargetNotShareable) {
+		return "Chirpy to " + kind + " doesn`t found"
+	}
+	if kind == targetQuote {
+		return "Only public and unlisted chirps can be quoted"
+	}
+	return "Only public and unlisted chirps can be rechirped"
+}
+
+/**
+ * Respond with error of loadTarget for new chirp, false when the error is written
+ */
+func respondWithTargetError(w http.ResponseWriter, kind string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errTargetNotFound):
+		respondWithError(w, 404, targetMessage(kind, err))
+	case errors.Is(err, errTargetNotShareable):
+		respondWithError(w, 400, targetMessage(kind, err))
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	}
+	return false
+}
+
+/**
+ * Parse visibility of new chirp, chirps are public by default
+ */
+func parseVisibility(visibility string) (string, bool) {
+	switch visibility {
+	case "":
+		return visibilityPublic, true
+	case visibilityPublic, visibilityUnlisted, visibilityFollowers, visibilityMentioned:
+		return visibility, true
+	}
+	return "", false
+}
+
+/**
+ * Load everything what is shown with chirps: quoted and rechirped chirps, reactions, attachments, polls, mentioned users.
+ * Authors are loaded only with ?include=author
+ */
+func (confg *apiConfig) enrichChirps(r *http.Request, viewerID uuid.UUID, chirps []Chirpy) error {
+	ctx := r.Context()
+	err := confg.withReferences(ctx, viewerID, chirps)
+	if err != nil {
+		return err
+	}
+
+	targets := make([]*Chirpy, 0, len(chirps))
+	for i := range chirps {
+		targets = append(targets, &chirps[i])
+		if chirps[i].Referenced != nil && !chirps[i].Referenced.Deleted {
+			targets = append(targets, chirps[i].Referenced)
+		}
+	}
+
+	err = confg.withReactions(ctx, viewerID, targets)
+	if err != nil {
+		return err
+	}
+
+	err = confg.withAttachments(ctx, targets)
+	if err != nil {
+		return err
+	}
+
+	err = confg.withPolls(ctx, viewerID, targets)
+	if err != nil {
+		return err
+	}
+
+	err = confg.withBookmarks(ctx, viewerID, targets)
+	if err != nil {
+		return err
+	}
+
+	err = confg.withPins(ctx, targets)
+	if err != nil {
+		return err
+	}
+
+	if isIncluded(r, "author") {
+		err = confg.withAuthors(ctx, targets)
+		if err != nil {
+			return err
+		}
+	}
+
+	return confg.withMentions(ctx, targets)
+}
+
+/**
+ * Embed quoted or rechirped chirps. Deleted chirp is embedded as a tombstone
+ */
+func (confg *apiConfig) withReferences(ctx context.Context, viewerID uuid.UUID, chirps []Chirpy) error {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if refID := chirp.referencedID(); refID != nil {
+			ids = append(ids, *refID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	referenced, err := confg.db.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{
+		Ids:      ids,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]database.Chirp, len(referenced))
+	for _, chirp := range referenced {
+		byID[chirp.ID] = chirp
+	}
+
+	for i := range chirps {
+		refID := chirps[i].referencedID()
+		if refID == nil {
+			continue
+		}
+
+		embedded := Chirpy{ID: *refID, Deleted: true, Reactions: map[string]int64{}, Entities: []Entity{}}
+		if chirp, ok := byID[*refID]; ok && !chirp.DeletedAt.Valid {
+			embedded = toChirpy(chirp)
+		}
+		chirps[i].Referenced = &embedded
+	}
+
+	return nil
+}
+
+/**
+ * Id of quoted or rechirped chirp, nil for regular chirp
+ */
+func (chirp Chirpy) referencedID() *uuid.UUID {
+	if chirp.QuoteOf != nil {
+		return chirp.QuoteOf
+	}
+	return chirp.RechirpOf
+}
+
+/**
+ * Handle create chirp, chirp with publish_at is scheduled
  */
 func (confg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request) {
+	confg.createChirp(w, r, false)
+}
+
+/**
+ * Create published, scheduled or draft chirp
+ */
+func (confg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request, draft bool) {
 	type requstChirpy struct {
-		Body   string `json:"body"`
+		Body       string       `json:"body"`
+		InReplyTo  *uuid.UUID   `json:"in_reply_to"`
+		QuoteOf    *uuid.UUID   `json:"quote_of"`
+		RechirpOf  *uuid.UUID   `json:"rechirp_of"`
+		MediaIDs   []uuid.UUID  `json:"media_ids"`
+		PublishAt  *time.Time   `json:"publish_at"`
+		Poll       *PollRequest `json:"poll"`
+		Visibility string       `json:"visibility"`
 	}
 
 	token, err := auth.GetBearerToken(r.Header)
@@ -46,86 +396,334 @@ func (confg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request
 	}
 
 	//Validate chirp
-	if len(chirpReq.Body) > 140 {
-		respondWithError(w, 400, "Chirp is too long")
+	limits, err := confg.userLimits(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !respondWithLimitError(w, limits.check(chirpReq.Body)) {
+		return
+	}
+
+	//Rechirp is only a reference to other chirp
+	if chirpReq.RechirpOf != nil && (chirpReq.Body != "" || chirpReq.QuoteOf != nil || chirpReq.InReplyTo != nil || len(chirpReq.MediaIDs) > 0 || chirpReq.Poll != nil) {
+		respondWithError(w, 400, "Rechirp can`t have body, quote, reply, media or poll")
+		return
+	}
+
+	if msg, ok := confg.validateMedia(r.Context(), userID, chirpReq.MediaIDs); !ok {
+		respondWithError(w, 400, msg)
+		return
+	}
+
+	visibility, ok := parseVisibility(chirpReq.Visibility)
+	if !ok {
+		respondWithError(w, 400, "Unknown visibility")
+		return
+	}
+
+	status := chirpPublished
+	publishAt := sql.NullTime{}
+	if draft {
+		if chirpReq.PublishAt != nil {
+			respondWithError(w, 400, "Draft can`t have publish_at, schedule it on publish")
+			return
+		}
+		status = chirpDraft
+	} else if chirpReq.PublishAt != nil {
+		if !chirpReq.PublishAt.After(time.Now()) {
+			respondWithError(w, 400, "publish_at must be in the future")
+			return
+		}
+		status = chirpScheduled
+		publishAt = sql.NullTime{Time: chirpReq.PublishAt.UTC(), Valid: true}
+	}
+
+	if chirpReq.Poll != nil {
+		//Options are checked by moderation like body, but they are never flagged.
+		//Masked options are validated, so they fit the column
+		for i, option := range chirpReq.Poll.Options {
+			if i == maxPollOptions {
+				break
+			}
+			moderatedOption, err := confg.validateMsg(r.Context(), option)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+				return
+			}
+			if moderatedOption.Action == moderation.ActionReject {
+				respondWithError(w, 400, "Poll option is rejected by moderation")
+				return
+			}
+			chirpReq.Poll.Options[i] = moderatedOption.Text
+		}
+
+		pollStart := time.Now()
+		if publishAt.Valid {
+			pollStart = publishAt.Time
+		}
+		if msg, ok := validatePoll(chirpReq.Poll, pollStart); !ok {
+			respondWithError(w, 400, msg)
+			return
+		}
+	}
+
+	moderated, err := confg.validateMsg(r.Context(), chirpReq.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
+	if moderated.Action == moderation.ActionReject {
+		respondWithError(w, 400, "Chirp is rejected by moderation")
+		return
+	}
+	newMsg := moderated.Text
+
+	quoteOf := uuid.NullUUID{}
+	if chirpReq.QuoteOf != nil {
+		quoted, err := confg.loadTarget(r.Context(), userID, *chirpReq.QuoteOf, targetQuote)
+		if !respondWithTargetError(w, targetQuote, err) {
+			return
+		}
+		quoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
+	rechirpOf := uuid.NullUUID{}
+	if chirpReq.RechirpOf != nil {
+		original, err := confg.loadTarget(r.Context(), userID, *chirpReq.RechirpOf, targetRechirp)
+		if !respondWithTargetError(w, targetRechirp, err) {
+			return
+		}
+		//Rechirp of rechirp points to the original chirp
+		if original.RechirpOf.Valid {
+			rechirpOf = original.RechirpOf
+		} else {
+			rechirpOf = uuid.NullUUID{UUID: original.ID, Valid: true}
+		}
+	}
 
-	newMsg := validateMsg(chirpReq.Body)
+	//Reply can be only to existing chirp
+	inReplyTo := uuid.NullUUID{}
+	if chirpReq.InReplyTo != nil {
+		parent, err := confg.loadTarget(r.Context(), userID, *chirpReq.InReplyTo, targetReply)
+		if !respondWithTargetError(w, targetReply, err) {
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
 
-	//Create chirp
-	chirpyDb, err := confg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   newMsg,
-		UserID: userID,
+	//Create chirp with everything what belongs to it in one transaction
+	var chirpyDb database.Chirp
+	err = confg.inTx(r.Context(), func(q *database.Queries) error {
+		chirpyDb, err = q.CreateChirp(r.Context(), database.CreateChirpParams{
+			Body:       newMsg,
+			UserID:     userID,
+			InReplyTo:  inReplyTo,
+			QuoteOf:    quoteOf,
+			RechirpOf:  rechirpOf,
+			Status:     status,
+			PublishAt:  publishAt,
+			Visibility: visibility,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = saveEntities(r.Context(), q, chirpyDb)
+		if err != nil {
+			return err
+		}
+
+		err = flagChirp(r.Context(), q, chirpyDb.ID, moderated)
+		if err != nil {
+			return err
+		}
+
+		err = attachMedia(r.Context(), q, chirpyDb, chirpReq.MediaIDs)
+		if err != nil {
+			return err
+		}
+
+		return createPoll(r.Context(), q, chirpyDb.ID, chirpReq.Poll)
 	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Chirp is already rechirped")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if isPublic(chirpyDb) {
+		confg.emit(r.Context(), events.Event{Type: events.ChirpPublished, ActorID: userID, ChirpID: chirpyDb.ID})
+	}
 
 	//Conver to json convertable format
-	chirpy := Chirpy{
-		ID:        chirpyDb.ID,
-		CreateAt:  chirpyDb.CreatedAt.String(),
-		UpdatedAt: chirpyDb.UpdatedAt.String(),
-		Body:      chirpyDb.Body,
-		UserID:    chirpyDb.UserID,
+	chirpsResponse := []Chirpy{toChirpy(chirpyDb)}
+	err = confg.enrichChirps(r, userID, chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, chirpy)
+	respondWithJSON(w, http.StatusCreated, chirpsResponse[0])
 
 }
 
 /**
  * Handle get one chirp by id
- */ 
+ */
 func (confg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request) {
-	chirpID := r.PathValue("chirpID")
-	if chirpID == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing chirpID")
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	viewerID := confg.viewerID(r)
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
+	if err != nil || !canView(chirp, viewerID) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
 		return
 	}
 
-	chirp, err := confg.db.GetChirpByID(r.Context(), uuid.MustParse(chirpID))
+	chirpsResponse := []Chirpy{toChirpy(chirp)}
+	err = confg.enrichChirps(r, viewerID, chirpsResponse)
 	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse[0])
+}
+
+/**
+ * Handle get thread of chirp: chain of parents from the root and all replies.
+ * Replies are flat and ordered by creation time, use in_reply_to to build the tree
+ */
+func (confg *apiConfig) handleGetChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	// Thread is opened on published chirp, deleted one stays in it as a tombstone, or on own draft.
+	// Parents and replies which the viewer can't see are left out
+	viewerID := confg.viewerID(r)
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
+	if err != nil || (chirp.Status != chirpPublished && chirp.UserID != viewerID) {
 		respondWithError(w, 404, "Chirpy doesn`t found")
 		return
 	}
 
-	//Convert database.Chirpy to Chirpy model for json response with correct format fields
-	chirpy := Chirpy{
-		ID:        chirp.ID,
-		CreateAt:  chirp.CreatedAt.String(),
-		UpdatedAt: chirp.UpdatedAt.String(),
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	ancestors, err := confg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	replies, err := confg.db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+		ViewerID: viewerID,
+		ID:       chirpID,
+		MaxRows:  maxThreadReplies,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	//All chirps of thread in one list to load reactions at once
+	chirpsResponse := make([]Chirpy, 0, len(ancestors)+1+len(replies))
+	for _, ancestor := range ancestors {
+		chirpsResponse = append(chirpsResponse, toChirpy(database.Chirp(ancestor)))
+	}
+	chirpsResponse = append(chirpsResponse, toChirpy(chirp))
+	for _, reply := range replies {
+		chirpsResponse = append(chirpsResponse, toChirpy(database.Chirp(reply)))
 	}
 
-	respondWithJSON(w, http.StatusOK, chirpy)
+	err = confg.enrichChirps(r, viewerID, chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	thread := ChirpThread{
+		Ancestors: chirpsResponse[:len(ancestors)],
+		Chirp:     chirpsResponse[len(ancestors)],
+		Replies:   chirpsResponse[len(ancestors)+1:],
+	}
+
+	respondWithJSON(w, http.StatusOK, thread)
 }
 
 /**
- * Handle get all chirps
+ * Handle get all chirps. The list is paginated by cursor, next page is sent in Link header
  */
 func (confg *apiConfig) handleGetAllChirps(w http.ResponseWriter, r *http.Request) {
 
 	authorId := r.URL.Query().Get("author_id")
 	sortBy := r.URL.Query().Get("sort")
-	if sortBy != "asc" && sortBy != "desc" {
-		sortBy = "asc"
+	desc := sortBy == "desc"
+
+	limit, cursor, err := parsePage(r, desc)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	sortBy = "created_at " + sortBy
-	
+	// One extra row tells if there is a next page
+	pageLimit := int32(limit + 1)
+
 	var chirps []database.Chirp
-	var err error
+	var pinned []database.Chirp
 
 	if authorId != "" {
-		chirps, err = confg.db.GetChirpsByUserID(r.Context(), database.GetChirpsByUserIDParams{
-			UserID:  uuid.MustParse(authorId),
-			Column2: sortBy,
-		})
+		userID, err := uuid.Parse(authorId)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author_id")
+			return
+		}
+
+		params := database.GetChirpsPageByUserIDParams{
+			UserID:          userID,
+			ViewerID:        confg.viewerID(r),
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       pageLimit,
+		}
+		if desc {
+			chirps, err = confg.db.GetChirpsPageByUserIDDesc(r.Context(), database.GetChirpsPageByUserIDDescParams(params))
+		} else {
+			chirps, err = confg.db.GetChirpsPageByUserID(r.Context(), params)
+		}
+
+		// Pinned chirps are shown first, only on the first page
+		if err == nil && r.URL.Query().Get("cursor") == "" {
+			pinned, err = confg.pinnedChirps(r.Context(), userID, confg.viewerID(r))
+		}
 	} else {
-		if sortBy == "created_at asc" {
-			chirps, err = confg.db.GetAllChirps(r.Context())
+		params := database.GetChirpsPageParams{
+			ViewerID:        confg.viewerID(r),
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       pageLimit,
+		}
+		if desc {
+			chirps, err = confg.db.GetChirpsPageDesc(r.Context(), database.GetChirpsPageDescParams(params))
 		} else {
-			chirps, err = confg.db.GetAllChirpsDesc(r.Context())
+			chirps, err = confg.db.GetChirpsPage(r.Context(), params)
 		}
 	}
 
@@ -134,25 +732,178 @@ func (confg *apiConfig) handleGetAllChirps(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	//Convert database.Chirpy to Chirpy model for json response with correct format fields
-	chirpsResponse := make([]Chirpy, len(chirps))
-	for i, chirp := range chirps {
-		chirpsResponse[i] = Chirpy{
-			ID:        chirp.ID,
-			CreateAt:  chirp.CreatedAt.String(),
-			UpdatedAt: chirp.UpdatedAt.String(),
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+	confg.respondWithPinnedChirpPage(w, r, pinned, chirps, limit)
+}
+
+/**
+ * Handle home timeline of authenticated user: chirps of followed users, newest first
+ */
+func (confg *apiConfig) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := confg.db.GetTimeline(r.Context(), database.GetTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.respondWithChirpPage(w, r, chirps, limit)
+}
+
+/**
+ * Respond with page of chirps ordered by (created_at, id). The query must return limit+1 rows,
+ * the extra row means that there is a next page
+ */
+func (confg *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, chirps []database.Chirp, limit int) {
+	confg.respondWithPinnedChirpPage(w, r, nil, chirps, limit)
+}
+
+/**
+ * Respond with pinned chirps followed by page of chirps. Pinned chirps are not counted in the limit
+ * and stay on their place in the page too
+ */
+func (confg *apiConfig) respondWithPinnedChirpPage(w http.ResponseWriter, r *http.Request, pinned, chirps []database.Chirp, limit int) {
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		last := chirps[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	chirpsResponse := make([]Chirpy, 0, len(pinned)+len(chirps))
+	for _, chirp := range pinned {
+		chirpsResponse = append(chirpsResponse, toChirpy(chirp))
+	}
+	for _, chirp := range chirps {
+		chirpsResponse = append(chirpsResponse, toChirpy(chirp))
+	}
+
+	err := confg.enrichChirps(r, confg.viewerID(r), chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
+
+/**
+ * Handle full-text search of chirps. Results are ordered by relevance and paginated by cursor
+ */
+func (confg *apiConfig) handleSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	searchQuery := strings.TrimSpace(query.Get("q"))
+	if searchQuery == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing q")
+		return
+	}
+
+	params := database.SearchChirpsParams{Query: searchQuery, ViewerID: confg.viewerID(r)}
+
+	if authorId := query.Get("author_id"); authorId != "" {
+		userID, err := uuid.Parse(authorId)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author_id")
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	//Date range, RFC 3339
+	if since := query.Get("since"); since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid since")
+			return
+		}
+		params.CreatedSince = sql.NullTime{Time: sinceTime.UTC(), Valid: true}
+	}
+
+	if until := query.Get("until"); until != "" {
+		untilTime, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid until")
+			return
+		}
+		params.CreatedUntil = sql.NullTime{Time: untilTime.UTC(), Valid: true}
+	}
+
+	limit, err := paging.ParseLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	cursor := paging.Start(true)
+	if cursorParam := query.Get("cursor"); cursorParam != "" {
+		cursor, err = paging.Decode(cursorParam)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
 		}
 	}
 
+	params.CursorRank = cursor.Rank
+	params.CursorCreatedAt = cursor.CreatedAt
+	params.CursorID = cursor.ID
+	params.PageLimit = int32(limit + 1)
+
+	results, err := confg.db.SearchChirps(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		setNextPageLink(w, r, paging.Cursor{Rank: last.Rank, CreatedAt: last.Chirp.CreatedAt, ID: last.Chirp.ID})
+	}
+
+	chirpsResponse := make([]Chirpy, len(results))
+	for i, result := range results {
+		chirpsResponse[i] = toChirpy(result.Chirp)
+		chirpsResponse[i].Rank = result.Rank
+		chirpsResponse[i].Snippet = result.Snippet
+	}
+
+	err = confg.enrichChirps(r, confg.viewerID(r), chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, chirpsResponse)
 }
 
 /**
- * Handle delete chirp
+ * Handle update chirp. The previous body is kept in chirp_revisions
  */
-func (confg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+func (confg *apiConfig) handleUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	type requstChirpy struct {
+		Body string `json:"body"`
+	}
+
 	//Authenticate user
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -167,14 +918,17 @@ func (confg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request
 	}
 
 	//Find a chirp
-	chirpID := r.PathValue("chirpID")
-	if chirpID == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing chirpID")
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
 		return
 	}
 
-	chirp, err := confg.db.GetChirpByID(r.Context(), uuid.MustParse(chirpID))	
-	if err != nil {
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil || !canView(chirp, userID) {
 		respondWithError(w, 404, "Chirpy doesn`t found")
 		return
 	}
@@ -185,34 +939,166 @@ func (confg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	//Delete chirp
-	err = confg.db.DeleteChirpByID(r.Context(), uuid.MustParse(chirpID))
+	if chirp.RechirpOf.Valid {
+		respondWithError(w, 400, "Rechirp can`t be edited")
+		return
+	}
 
+	//Decode request
+	var chirpReq requstChirpy
+	err = json.NewDecoder(r.Body).Decode(&chirpReq)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	respondWithJSON(w, 204, nil)
+	//Validate chirp
+	limits, err := confg.userLimits(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !respondWithLimitError(w, limits.check(chirpReq.Body)) {
+		return
+	}
+
+	moderated, err := confg.validateMsg(r.Context(), chirpReq.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if moderated.Action == moderation.ActionReject {
+		respondWithError(w, 400, "Chirp is rejected by moderation")
+		return
+	}
+	newMsg := moderated.Text
+
+	//Update chirp, nothing to save when body is the same
+	if newMsg != chirp.Body {
+		err = confg.inTx(r.Context(), func(q *database.Queries) error {
+			chirp, err = q.UpdateChirp(r.Context(), database.UpdateChirpParams{
+				Body: newMsg,
+				ID:   chirpID,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = saveEntities(r.Context(), q, chirp)
+			if err != nil {
+				return err
+			}
+
+			return reflagChirp(r.Context(), q, chirp.ID, moderated)
+		})
+		if err != nil {
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+	}
+
+	chirpsResponse := []Chirpy{toChirpy(chirp)}
+	err = confg.enrichChirps(r, userID, chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse[0])
 }
 
 /**
- * Validate message and replace bad words with ****
+ * Handle get edit history of chirp, oldest first
  */
-func validateMsg(msg string) string {
-	listOfBadWords := []string{"kerfuffle", "sharbert", "fornax"}
+func (confg *apiConfig) handleGetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
 
-	words := strings.Split(msg, " ")
-	newMsg := []string{}
-	for _, word := range words {
-		if containe(listOfBadWords, word) {
-			newMsg = append(newMsg, "****")
-			continue
+	// History is shown to those who can see the chirp
+	viewerID := confg.viewerID(r)
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
+	if err != nil || !canView(chirp, viewerID) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	revisions, err := confg.db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	revisionsResponse := make([]ChirpRevision, len(revisions))
+	for i, revision := range revisions {
+		revisionsResponse[i] = ChirpRevision{
+			ID:         revision.ID,
+			ChirpID:    revision.ChirpID,
+			Body:       revision.Body,
+			CreatedAt:  revision.CreatedAt.String(),
+			ReplacedAt: revision.ReplacedAt.String(),
 		}
-		newMsg = append(newMsg, word)
 	}
 
-	return strings.Join(newMsg, " ")
+	respondWithJSON(w, http.StatusOK, revisionsResponse)
+}
+
+/**
+ * Handle delete chirp
+ */
+func (confg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	//Authenticate user
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	//Find a chirp
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil || !canView(chirp, userID) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	//Check if user is owner of chirp
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "Forbidden")
+		return
+	}
+
+	//Chirp is moved to trash and can be restored, purger removes it later
+	err = confg.db.SoftDeleteChirpByID(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if isPublic(chirp) {
+		confg.emit(r.Context(), events.Event{Type: events.ChirpDeleted, ActorID: userID, ChirpID: chirp.ID})
+	}
+
+	respondWithJSON(w, 204, nil)
 }
 
 /**
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/moderation"
+	"github.com/google/uuid"
+)
+
+/**
+ * Answer queries of details kept beside chirps: reactions, tags, mentions, media, polls, bookmarks and pins.
+ * There are none
+ */
+func onChirpDetails(db *fakeDB) {
+	for _, name := range []string{
+		"GetReactionCounts", "GetViewerReactions",
+		"DeleteChirpTags", "DeleteChirpMentions", "GetMentionsByChirpIDs", "GetMediaByChirpIDs",
+		"GetPollsByChirpIDs", "GetViewerBookmarks", "GetPinnedChirpIDs",
+	} {
+		db.on(name, fakeResult{})
+	}
+}
+
+/**
+ * Answer GetVisibleChirpByID like the visibility SQL, the chirp isn't found for hidden viewers
+ */
+func onVisibleChirp(db *fakeDB, chirp database.Chirp, hiddenFor ...uuid.UUID) {
+	db.onArgs("GetVisibleChirpByID", func(args []driver.Value) fakeResult {
+		for _, id := range hiddenFor {
+			if hasArg(args, id) {
+				return fakeResult{}
+			}
+		}
+		return rows(chirp)
+	})
+}
+
+func TestUpdateChirp(t *testing.T) {
+	ownerID, chirpID := uuid.New(), uuid.New()
+	created := time.Now().Add(-time.Hour)
+	chirp := database.Chirp{ID: chirpID, CreatedAt: created, UpdatedAt: created, Body: "old body", UserID: ownerID, Status: chirpPublished}
+	updated := chirp
+	updated.Body, updated.UpdatedAt = "new body", time.Now()
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		target  string
+		body    string
+		found   bool
+		want    int
+		wantRun int
+	}{
+		{name: "invalid chirpID", userID: ownerID, target: "/api/chirps/abc", body: `{"body": "new body"}`, want: http.StatusBadRequest},
+		{name: "unknown chirp", userID: ownerID, body: `{"body": "new body"}`, want: http.StatusNotFound},
+		{name: "chirp of other user", userID: uuid.New(), body: `{"body": "new body"}`, found: true, want: http.StatusForbidden},
+		{name: "same body", userID: ownerID, body: `{"body": "old body"}`, found: true, want: http.StatusOK},
+		{name: "owner", userID: ownerID, body: `{"body": "new body"}`, found: true, want: http.StatusOK, wantRun: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.found {
+				onVisibleChirp(db, chirp)
+			} else {
+				db.on("GetVisibleChirpByID", fakeResult{})
+			}
+			db.on("UpdateChirp", rows(updated))
+			db.on("DeleteChirpFlag", fakeResult{})
+			db.on("GetUserByID", rows(database.User{ID: tt.userID}))
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			target := tt.target
+			if target == "" {
+				target = "/api/chirps/" + chirpID.String()
+			}
+			rec := serveTest("PUT /api/chirps/{chirpID}", cfg.handleUpdateChirp, target, testToken(t, tt.userID), tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			// Only a changed body of own chirp makes a revision
+			if got := db.ran("UpdateChirp"); got != tt.wantRun {
+				t.Errorf("chirp updated %d times, want %d", got, tt.wantRun)
+			}
+		})
+	}
+}
+
+func TestUpdateChirpResponse(t *testing.T) {
+	ownerID, chirpID := uuid.New(), uuid.New()
+	created := time.Now().Add(-time.Hour)
+	chirp := database.Chirp{ID: chirpID, CreatedAt: created, UpdatedAt: created, Body: "old body", UserID: ownerID, Status: chirpPublished}
+	updated := chirp
+	updated.Body, updated.UpdatedAt = "new body", time.Now()
+
+	db := newFakeDB()
+	onVisibleChirp(db, chirp)
+	db.on("UpdateChirp", rows(updated))
+	db.on("DeleteChirpFlag", fakeResult{})
+	db.on("GetUserByID", rows(database.User{ID: ownerID}))
+	onChirpDetails(db)
+	cfg := newTestConfig(t, db)
+
+	rec := serveTest("PUT /api/chirps/{chirpID}", cfg.handleUpdateChirp, "/api/chirps/"+chirpID.String(),
+		testToken(t, ownerID), `{"body": "new body"}`)
+	var got Chirpy
+	decodeResponse(t, rec, http.StatusOK, &got)
+	if got.Body != "new body" || !got.Edited {
+		t.Errorf("response = %+v, want edited chirp with new body", got)
+	}
+}
+
+func TestUpdateChirpFlag(t *testing.T) {
+	ownerID, chirpID := uuid.New(), uuid.New()
+	chirp := database.Chirp{ID: chirpID, Body: "buy spam", UserID: ownerID, Status: chirpPublished}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantFlag   int
+		wantDelete int
+	}{
+		{name: "flagged text stays", body: "buy more spam", wantFlag: 1},
+		{name: "flagged text removed", body: "buy bread", wantDelete: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := chirp
+			updated.Body = tt.body
+			db := newFakeDB()
+			onVisibleChirp(db, chirp)
+			db.on("UpdateChirp", rows(updated))
+			db.on("FlagChirp", fakeResult{Affected: 1})
+			db.on("DeleteChirpFlag", fakeResult{Affected: 1})
+			db.on("GetUserByID", rows(database.User{ID: ownerID}))
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+			cfg.moderator = moderation.Chain{moderation.WordFilter{Source: moderation.StaticWords{{Text: "spam", Action: moderation.ActionFlag}}}}
+
+			rec := serveTest("PUT /api/chirps/{chirpID}", cfg.handleUpdateChirp, "/api/chirps/"+chirpID.String(),
+				testToken(t, ownerID), `{"body": "`+tt.body+`"}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if db.ran("FlagChirp") != tt.wantFlag || db.ran("DeleteChirpFlag") != tt.wantDelete {
+				t.Errorf("flagged %d times and flag removed %d times, want %d and %d",
+					db.ran("FlagChirp"), db.ran("DeleteChirpFlag"), tt.wantFlag, tt.wantDelete)
+			}
+		})
+	}
+}
+
+func TestGetChirpRevisions(t *testing.T) {
+	ownerID, blockedID, chirpID := uuid.New(), uuid.New(), uuid.New()
+	published := database.Chirp{ID: chirpID, Body: "third", UserID: ownerID, Status: chirpPublished}
+	draft := database.Chirp{ID: chirpID, Body: "third", UserID: ownerID, Status: chirpDraft}
+	deleted := published
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	first := database.ChirpRevision{ID: uuid.New(), ChirpID: chirpID, Body: "first", ReplacedAt: time.Now().Add(-time.Hour)}
+	second := database.ChirpRevision{ID: uuid.New(), ChirpID: chirpID, Body: "second", ReplacedAt: time.Now()}
+
+	tests := []struct {
+		name  string
+		chirp *database.Chirp
+		token string
+		want  int
+	}{
+		{name: "unknown chirp", want: http.StatusNotFound},
+		{name: "published", chirp: &published, want: http.StatusOK},
+		{name: "viewer blocked by owner", chirp: &published, token: testToken(t, blockedID), want: http.StatusNotFound},
+		{name: "draft of other user", chirp: &draft, token: testToken(t, uuid.New()), want: http.StatusNotFound},
+		{name: "draft without token", chirp: &draft, want: http.StatusNotFound},
+		{name: "own draft", chirp: &draft, token: testToken(t, ownerID), want: http.StatusOK},
+		// History of chirp in trash is gone with the chirp
+		{name: "own deleted chirp", chirp: &deleted, token: testToken(t, ownerID), want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.chirp != nil {
+				onVisibleChirp(db, *tt.chirp, blockedID)
+			} else {
+				db.on("GetVisibleChirpByID", fakeResult{})
+			}
+			db.on("GetChirpRevisions", rows(first, second))
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("GET /api/chirps/{chirpID}/revisions", cfg.handleGetChirpRevisions, "/api/chirps/"+chirpID.String()+"/revisions", tt.token, "")
+			if tt.want != http.StatusOK {
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+				}
+				return
+			}
+
+			// Oldest first
+			var got []ChirpRevision
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if len(got) != 2 || got[0].Body != "first" || got[1].Body != "second" {
+				t.Errorf("revisions = %+v, want first and second", got)
+			}
+		})
+	}
+}
+
+func TestCreateChirpVisibility(t *testing.T) {
+	authorID, otherID := uuid.New(), uuid.New()
+	shared := func(visibility string) database.Chirp {
+		return database.Chirp{ID: uuid.New(), Body: "original", UserID: otherID, Status: chirpPublished, Visibility: visibility}
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		target database.Chirp
+		want   int
+		// Visibility of created chirp
+		wantVisibility string
+	}{
+		{name: "default", body: `{"body": "hello"}`, want: http.StatusCreated, wantVisibility: visibilityPublic},
+		{name: "followers", body: `{"body": "hello", "visibility": "followers"}`, want: http.StatusCreated, wantVisibility: visibilityFollowers},
+		{name: "unknown", body: `{"body": "hello", "visibility": "friends"}`, want: http.StatusBadRequest},
+		{name: "quote of unlisted", body: `{"body": "hello", "quote_of": "%s"}`, target: shared(visibilityUnlisted), want: http.StatusCreated, wantVisibility: visibilityPublic},
+		{name: "quote of followers-only", body: `{"body": "hello", "quote_of": "%s"}`, target: shared(visibilityFollowers), want: http.StatusBadRequest},
+		{name: "rechirp of mentioned-only", body: `{"rechirp_of": "%s"}`, target: shared(visibilityMentioned), want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.on("GetUserByID", rows(database.User{ID: authorID}))
+			onVisibleChirp(db, tt.target)
+			db.on("GetVisibleChirpsByIDs", rows(tt.target))
+			db.onArgs("CreateChirp", func(args []driver.Value) fakeResult {
+				created := database.Chirp{ID: uuid.New(), UserID: authorID, Body: "hello", Status: chirpPublished}
+				for _, visibility := range []string{visibilityPublic, visibilityUnlisted, visibilityFollowers, visibilityMentioned} {
+					if hasArg(args, visibility) {
+						created.Visibility = visibility
+					}
+				}
+				return rows(created)
+			})
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			body := tt.body
+			if tt.target.ID != uuid.Nil {
+				body = fmt.Sprintf(body, tt.target.ID)
+			}
+			rec := serveTest("POST /api/chirps", cfg.handleCreateChirp, "/api/chirps", testToken(t, authorID), body)
+			if tt.want != http.StatusCreated {
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				if db.ran("CreateChirp") != 0 {
+					t.Error("chirp was created")
+				}
+				return
+			}
+
+			var got Chirpy
+			decodeResponse(t, rec, http.StatusCreated, &got)
+			if got.Visibility != tt.wantVisibility {
+				t.Errorf("visibility = %q, want %q", got.Visibility, tt.wantVisibility)
+			}
+		})
+	}
+}
+
+func TestGetChirpThread(t *testing.T) {
+	ownerID, blockedID, chirpID := uuid.New(), uuid.New(), uuid.New()
+	published := database.Chirp{ID: chirpID, Body: "hello", UserID: ownerID, Status: chirpPublished}
+	deleted := published
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	draft := published
+	draft.Status = chirpDraft
+	// Author of the reply blocked one viewer
+	reply := database.GetChirpDescendantsRow{ID: uuid.New(), Body: "reply", UserID: uuid.New(), Status: chirpPublished,
+		InReplyTo: uuid.NullUUID{UUID: chirpID, Valid: true}}
+
+	tests := []struct {
+		name        string
+		chirp       database.Chirp
+		viewerID    uuid.UUID
+		want        int
+		wantDeleted bool
+		wantReplies int
+	}{
+		{name: "published", chirp: published, viewerID: uuid.New(), want: http.StatusOK, wantReplies: 1},
+		{name: "reply hidden from viewer", chirp: published, viewerID: blockedID, want: http.StatusOK},
+		{name: "deleted chirp with replies", chirp: deleted, viewerID: uuid.New(), want: http.StatusOK, wantDeleted: true, wantReplies: 1},
+		{name: "draft of other user", chirp: draft, viewerID: uuid.New(), want: http.StatusNotFound},
+		{name: "own draft", chirp: draft, viewerID: ownerID, want: http.StatusOK, wantReplies: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			onVisibleChirp(db, tt.chirp)
+			db.on("GetChirpAncestors", fakeResult{})
+			db.onArgs("GetChirpDescendants", func(args []driver.Value) fakeResult {
+				if hasArg(args, blockedID) {
+					return fakeResult{}
+				}
+				return rows(reply)
+			})
+			db.on("GetVisibleChirpsByIDs", fakeResult{})
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("GET /api/chirps/{chirpID}/thread", cfg.handleGetChirpThread, "/api/chirps/"+chirpID.String()+"/thread", testToken(t, tt.viewerID), "")
+			if tt.want != http.StatusOK {
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				return
+			}
+
+			var got ChirpThread
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if got.Chirp.ID != chirpID || got.Chirp.Deleted != tt.wantDeleted || len(got.Replies) != tt.wantReplies {
+				t.Errorf("thread of %s with deleted = %v and %d replies, want %s with deleted = %v and %d replies",
+					got.Chirp.ID, got.Chirp.Deleted, len(got.Replies), chirpID, tt.wantDeleted, tt.wantReplies)
+			}
+			if tt.wantDeleted && got.Chirp.Body != "" {
+				t.Errorf("body of deleted chirp = %q, want it hidden", got.Chirp.Body)
+			}
+		})
+	}
+}
+
+func TestGetAllChirpsPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      int
+		wantQuery string
+	}{
+		{name: "oldest first", query: "", want: http.StatusOK, wantQuery: "GetChirpsPage"},
+		{name: "newest first", query: "?sort=desc&limit=5", want: http.StatusOK, wantQuery: "GetChirpsPageDesc"},
+		{name: "invalid limit", query: "?limit=nope", want: http.StatusBadRequest},
+		{name: "invalid cursor", query: "?cursor=nope", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.on("GetChirpsPage", fakeResult{})
+			db.on("GetChirpsPageDesc", fakeResult{})
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("GET /api/chirps", cfg.handleGetAllChirps, "/api/chirps"+tt.query, "", "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantQuery != "" && db.ran(tt.wantQuery) != 1 {
+				t.Errorf("%s ran %d times, want once", tt.wantQuery, db.ran(tt.wantQuery))
+			}
+			if tt.want == http.StatusBadRequest && db.ran("GetChirpsPage")+db.ran("GetChirpsPageDesc") != 0 {
+				t.Errorf("page was loaded for invalid request")
+			}
+		})
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/moderation"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/St5/goboot-srv/internal/textlen"
+	"github.com/google/uuid"
+)
+
+const (
+	// Max number of users in conversation with the creator
+	maxConversationParticipants = 10
+	// Max length of message in user-perceived characters
+	maxMessageLength = 1000
+)
+
+type Conversation struct {
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Participants []Participant `json:"participants"`
+	UnreadCount  int64         `json:"unread_count"`
+}
+
+type Participant struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func toMessage(message database.Message) Message {
+	return Message{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+		CreatedAt:      message.CreatedAt,
+	}
+}
+
+/**
+ * Key of 1:1 conversation, it is the same for both users
+ */
+func directKey(userID, otherID uuid.UUID) sql.NullString {
+	ids := []string{userID.String(), otherID.String()}
+	if ids[1] < ids[0] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	return sql.NullString{String: strings.Join(ids, ":"), Valid: true}
+}
+
+/**
+ * Handle create conversation with other users. There is only one 1:1 conversation for a pair of users,
+ * the existing one is returned
+ */
+func (cfg *apiConfig) handleCreateConversation(w http.ResponseWriter, r *http.Request) {
+	type requestConversation struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	var conversationReq requestConversation
+	err = json.NewDecoder(r.Body).Decode(&conversationReq)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	others := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range conversationReq.ParticipantIDs {
+		if !seen[id] {
+			seen[id] = true
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		respondWithError(w, 400, "Conversation needs other participants")
+		return
+	}
+	if len(others)+1 > maxConversationParticipants {
+		respondWithError(w, 400, "Too many participants")
+		return
+	}
+
+	count, err := cfg.db.CountUsersByIDs(r.Context(), others)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if count != int64(len(others)) {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	blocked, err := cfg.db.HasBlockBetween(r.Context(), database.HasBlockBetweenParams{
+		UserIds: others,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "You can`t start a conversation with these users")
+		return
+	}
+
+	key := sql.NullString{}
+	if len(others) == 1 {
+		key = directKey(userID, others[0])
+		existing, err := cfg.db.GetConversationByDirectKey(r.Context(), key)
+		if err == nil {
+			cfg.respondWithConversation(w, r, userID, existing, 200)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+	}
+
+	var conversation database.Conversation
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		conversation, err = q.CreateConversation(r.Context(), database.CreateConversationParams{
+			CreatedBy: userID,
+			DirectKey: key,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.AddConversationParticipants(r.Context(), database.AddConversationParticipantsParams{
+			ConversationID: conversation.ID,
+			UserIds:        append(others, userID),
+		})
+	})
+	//Other user has just started the same 1:1 conversation
+	if isUniqueViolation(err) {
+		conversation, err = cfg.db.GetConversationByDirectKey(r.Context(), key)
+		if err == nil {
+			cfg.respondWithConversation(w, r, userID, conversation, 200)
+			return
+		}
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	cfg.respondWithConversation(w, r, userID, conversation, 201)
+}
+
+/**
+ * Handle list of conversations of authenticated user, latest message first
+ */
+func (cfg *apiConfig) handleGetConversations(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	conversations, err := cfg.db.GetConversationsByUserID(r.Context(), database.GetConversationsByUserIDParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if len(conversations) > limit {
+		conversations = conversations[:limit]
+		last := conversations[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.UpdatedAt, ID: last.ID})
+	}
+
+	response, err := cfg.toConversations(r.Context(), userID, conversations)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 200, response)
+}
+
+/**
+ * Handle get conversation, only participants can see it
+ */
+func (cfg *apiConfig) handleGetConversation(w http.ResponseWriter, r *http.Request) {
+	userID, conversation, ok := cfg.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	cfg.respondWithConversation(w, r, userID, conversation, 200)
+}
+
+/**
+ * Handle send message to conversation. Message is moderated like a chirp,
+ * it can't be sent when somebody in the conversation blocked the sender or is blocked by the sender
+ */
+func (cfg *apiConfig) handleCreateMessage(w http.ResponseWriter, r *http.Request) {
+	type requestMessage struct {
+		Body string `json:"body"`
+	}
+
+	userID, conversation, ok := cfg.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	var messageReq requestMessage
+	err := json.NewDecoder(r.Body).Decode(&messageReq)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(messageReq.Body) == "" {
+		respondWithError(w, 400, "Message is empty")
+		return
+	}
+	if textlen.Length(messageReq.Body, textlen.DefaultURLWeight) > maxMessageLength {
+		respondWithError(w, 400, "Message is too long")
+		return
+	}
+
+	moderated, err := cfg.validateMsg(r.Context(), messageReq.Body)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if moderated.Action == moderation.ActionReject {
+		respondWithError(w, 400, "Message is rejected by moderation")
+		return
+	}
+
+	blocked, err := cfg.db.HasBlockInConversation(r.Context(), database.HasBlockInConversationParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "You can`t send messages to this conversation")
+		return
+	}
+
+	var message database.Message
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		message, err = q.CreateMessage(r.Context(), database.CreateMessageParams{
+			ConversationID: conversation.ID,
+			SenderID:       userID,
+			Body:           moderated.Text,
+		})
+		if err != nil {
+			return err
+		}
+
+		//Sender has read everything before own message
+		return q.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+			ReadAt:         message.CreatedAt,
+			ConversationID: conversation.ID,
+			UserID:         userID,
+		})
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 201, toMessage(message))
+}
+
+/**
+ * Handle history of messages in conversation, newest first
+ */
+func (cfg *apiConfig) handleGetMessages(w http.ResponseWriter, r *http.Request) {
+	_, conversation, ok := cfg.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	messages, err := cfg.db.GetMessages(r.Context(), database.GetMessagesParams{
+		ConversationID:  conversation.ID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+		last := messages[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	response := make([]Message, len(messages))
+	for i, message := range messages {
+		response[i] = toMessage(message)
+	}
+
+	respondWithJSON(w, 200, response)
+}
+
+/**
+ * Handle move read marker of authenticated user. Optional body {"message_id"} marks messages
+ * up to this one as read, without it the whole conversation is read. Marker never moves back
+ */
+func (cfg *apiConfig) handleMarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	type requestRead struct {
+		MessageID *uuid.UUID `json:"message_id"`
+	}
+
+	userID, conversation, ok := cfg.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	//Body is optional
+	var readReq requestRead
+	err := json.NewDecoder(r.Body).Decode(&readReq)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	readAt := conversation.UpdatedAt
+	if readReq.MessageID != nil {
+		message, err := cfg.db.GetMessageByID(r.Context(), database.GetMessageByIDParams{
+			ID:             *readReq.MessageID,
+			ConversationID: conversation.ID,
+		})
+		if err != nil {
+			respondWithError(w, 404, "Message not found")
+			return
+		}
+		readAt = message.CreatedAt
+	}
+
+	err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ReadAt:         readAt,
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Get authenticated user and conversation from path. Conversation of other users is not found.
+ * Error response is already written when ok is false
+ */
+func (cfg *apiConfig) participantConversation(w http.ResponseWriter, r *http.Request) (uuid.UUID, database.Conversation, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return uuid.Nil, database.Conversation{}, false
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid conversationID")
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	conversation, err := cfg.db.GetConversationForUser(r.Context(), database.GetConversationForUserParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "Conversation not found")
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	return userID, conversation, true
+}
+
+func (cfg *apiConfig) respondWithConversation(w http.ResponseWriter, r *http.Request, userID uuid.UUID, conversation database.Conversation, code int) {
+	response, err := cfg.toConversations(r.Context(), userID, []database.Conversation{conversation})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, code, response[0])
+}
+
+/**
+ * Convert conversations for json response with participants and count of unread messages of the user
+ */
+func (cfg *apiConfig) toConversations(ctx context.Context, userID uuid.UUID, conversations []database.Conversation) ([]Conversation, error) {
+	ids := make([]uuid.UUID, len(conversations))
+	index := make(map[uuid.UUID]*Conversation, len(conversations))
+	response := make([]Conversation, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+		response[i] = Conversation{
+			ID:           conversation.ID,
+			CreatedAt:    conversation.CreatedAt,
+			UpdatedAt:    conversation.UpdatedAt,
+			Participants: []Participant{},
+		}
+		index[conversation.ID] = &response[i]
+	}
+	if len(ids) == 0 {
+		return response, nil
+	}
+
+	participants, err := cfg.db.GetConversationParticipants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, participant := range participants {
+		conversation := index[participant.ConversationID]
+		item := Participant{UserID: participant.UserID}
+		if participant.LastReadAt.Valid {
+			item.LastReadAt = &participant.LastReadAt.Time
+		}
+		conversation.Participants = append(conversation.Participants, item)
+	}
+
+	unread, err := cfg.db.GetUnreadCounts(ctx, database.GetUnreadCountsParams{
+		UserID:          userID,
+		ConversationIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range unread {
+		index[count.ConversationID].UnreadCount = count.Unread
+	}
+
+	return response, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+/**
+ * Answer GetConversationForUser like the query, conversation is found only for its participants
+ */
+func onConversation(db *fakeDB, conversation database.Conversation, participants ...uuid.UUID) {
+	db.onArgs("GetConversationForUser", func(args []driver.Value) fakeResult {
+		for _, id := range participants {
+			if hasArg(args, id) {
+				return rows(conversation)
+			}
+		}
+		return fakeResult{}
+	})
+	members := make([]any, len(participants))
+	for i, id := range participants {
+		members[i] = database.ConversationParticipant{ConversationID: conversation.ID, UserID: id, JoinedAt: conversation.CreatedAt}
+	}
+	db.on("GetConversationParticipants", rows(members...))
+	db.on("GetUnreadCounts", fakeResult{})
+}
+
+func TestCreateConversation(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	body := `{"participant_ids": ["` + otherID.String() + `"]}`
+
+	tests := []struct {
+		name    string
+		body    string
+		users   int64
+		blocked bool
+		want    int
+	}{
+		{name: "only self", body: `{"participant_ids": ["` + userID.String() + `"]}`, want: http.StatusBadRequest},
+		{name: "unknown user", body: body, users: 0, want: http.StatusNotFound},
+		// Block in any direction is found by the query
+		{name: "blocked", body: body, users: 1, blocked: true, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.on("CountUsersByIDs", fakeResult{Rows: [][]driver.Value{{tt.users}}})
+			db.on("HasBlockBetween", fakeResult{Rows: [][]driver.Value{{tt.blocked}}})
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/conversations", cfg.handleCreateConversation, "/api/conversations", testToken(t, userID), tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if db.ran("CreateConversation") != 0 {
+				t.Error("conversation was created")
+			}
+		})
+	}
+}
+
+func TestConversationOnlyForParticipants(t *testing.T) {
+	userID, otherID, strangerID := uuid.New(), uuid.New(), uuid.New()
+	conversation := database.Conversation{ID: uuid.New(), CreatedBy: userID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	target := "/api/conversations/" + conversation.ID.String()
+
+	handlers := []struct {
+		pattern string
+		path    string
+		body    string
+		handler func(*apiConfig, http.ResponseWriter, *http.Request)
+	}{
+		{"GET /api/conversations/{conversationID}", "", "", (*apiConfig).handleGetConversation},
+		{"POST /api/conversations/{conversationID}/messages", "/messages", `{"body": "hi"}`, (*apiConfig).handleCreateMessage},
+		{"GET /api/conversations/{conversationID}/messages", "/messages", "", (*apiConfig).handleGetMessages},
+		{"POST /api/conversations/{conversationID}/read", "/read", "{}", (*apiConfig).handleMarkConversationRead},
+	}
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		target string
+		want   int
+	}{
+		{name: "invalid conversationID", userID: userID, target: "/api/conversations/abc", want: http.StatusBadRequest},
+		{name: "not participant", userID: strangerID, target: target, want: http.StatusNotFound},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.pattern+" "+tt.name, func(t *testing.T) {
+				db := newFakeDB()
+				onConversation(db, conversation, userID, otherID)
+				cfg := newTestConfig(t, db)
+				handler := func(w http.ResponseWriter, r *http.Request) { h.handler(cfg, w, r) }
+
+				rec := serveTest(h.pattern, handler, tt.target+h.path, testToken(t, tt.userID), h.body)
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				// Nothing of the conversation is read or written
+				for _, name := range []string{"GetMessages", "CreateMessage", "MarkConversationRead"} {
+					if db.ran(name) != 0 {
+						t.Errorf("%s ran for %s", name, tt.name)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGetConversation(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	conversation := database.Conversation{ID: uuid.New(), CreatedBy: userID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	db := newFakeDB()
+	onConversation(db, conversation, userID, otherID)
+	cfg := newTestConfig(t, db)
+
+	rec := serveTest("GET /api/conversations/{conversationID}", cfg.handleGetConversation,
+		"/api/conversations/"+conversation.ID.String(), testToken(t, otherID), "")
+	var got Conversation
+	decodeResponse(t, rec, http.StatusOK, &got)
+	if got.ID != conversation.ID || len(got.Participants) != 2 {
+		t.Errorf("conversation = %+v, want %s with both participants", got, conversation.ID)
+	}
+}
+
+func TestCreateMessageBlocked(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	conversation := database.Conversation{ID: uuid.New(), CreatedBy: otherID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	db := newFakeDB()
+	onConversation(db, conversation, userID, otherID)
+	db.on("HasBlockInConversation", fakeResult{Rows: [][]driver.Value{{true}}})
+	cfg := newTestConfig(t, db)
+
+	rec := serveTest("POST /api/conversations/{conversationID}/messages", cfg.handleCreateMessage,
+		"/api/conversations/"+conversation.ID.String()+"/messages", testToken(t, userID), `{"body": "hi"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+	}
+	if db.ran("CreateMessage") != 0 {
+		t.Error("message was sent to conversation with block")
+	}
+}
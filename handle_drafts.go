@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/google/uuid"
+)
+
+/**
+ * Handle create draft, it is not shown to anyone except the owner until it is published
+ */
+func (confg *apiConfig) handleCreateDraft(w http.ResponseWriter, r *http.Request) {
+	confg.createChirp(w, r, true)
+}
+
+/**
+ * Handle list of drafts and scheduled chirps of the authenticated user, newest first
+ */
+func (confg *apiConfig) handleGetDrafts(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := confg.db.GetUnpublishedChirpsByUserID(r.Context(), database.GetUnpublishedChirpsByUserIDParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.respondWithChirpPage(w, r, chirps, limit)
+}
+
+/**
+ * Handle publish of draft or scheduled chirp. Without publish_at it is published now,
+ * with publish_at it is scheduled or rescheduled
+ */
+func (confg *apiConfig) handlePublishDraft(w http.ResponseWriter, r *http.Request) {
+	type requestPublish struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	//Body is optional
+	var publishReq requestPublish
+	err = json.NewDecoder(r.Body).Decode(&publishReq)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	chirp, err := confg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil || chirp.UserID != userID || chirp.Status == chirpPublished || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Draft doesn`t found")
+		return
+	}
+
+	if publishReq.PublishAt != nil && !publishReq.PublishAt.After(time.Now()) {
+		respondWithError(w, 400, "publish_at must be in the future")
+		return
+	}
+
+	pollStart := time.Now()
+	if publishReq.PublishAt != nil {
+		pollStart = *publishReq.PublishAt
+	}
+	ok, err := confg.pollAllowsPublish(r.Context(), chirpID, pollStart)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !ok {
+		respondWithError(w, 400, pollClosesAtMessage)
+		return
+	}
+
+	// Replied, quoted or rechirped chirp could be deleted or hidden from the author since the draft was saved
+	msg, err := confg.checkTargets(r.Context(), chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if msg != "" {
+		respondWithError(w, http.StatusConflict, msg)
+		return
+	}
+
+	if publishReq.PublishAt != nil {
+		chirp, err = confg.db.ScheduleChirp(r.Context(), database.ScheduleChirpParams{
+			ID:        chirpID,
+			PublishAt: sql.NullTime{Time: publishReq.PublishAt.UTC(), Valid: true},
+		})
+	} else {
+		chirp, err = confg.db.PublishChirp(r.Context(), chirpID)
+	}
+	//Publisher was faster
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Chirp is already published")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if isPublic(chirp) {
+		confg.emit(r.Context(), events.Event{Type: events.ChirpPublished, ActorID: userID, ChirpID: chirp.ID})
+	}
+
+	chirpsResponse := []Chirpy{toChirpy(chirp)}
+	err = confg.enrichChirps(r, userID, chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse[0])
+}
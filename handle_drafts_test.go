@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPublishDraftChecksTargets(t *testing.T) {
+	authorID, targetID := uuid.New(), uuid.New()
+	target := database.Chirp{ID: targetID, Body: "original", UserID: uuid.New(), Status: chirpPublished, Visibility: visibilityPublic}
+	followersOnly := target
+	followersOnly.Visibility = visibilityFollowers
+	deleted := target
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	reply := database.Chirp{ID: uuid.New(), Body: "reply", UserID: authorID, Status: chirpDraft, InReplyTo: uuid.NullUUID{UUID: targetID, Valid: true}}
+	quote := database.Chirp{ID: uuid.New(), Body: "quote", UserID: authorID, Status: chirpDraft, QuoteOf: uuid.NullUUID{UUID: targetID, Valid: true}}
+
+	tests := []struct {
+		name   string
+		draft  database.Chirp
+		target *database.Chirp
+		want   int
+	}{
+		// Author of the target blocked the author of the draft
+		{name: "reply to hidden chirp", draft: reply, want: http.StatusConflict},
+		{name: "reply to deleted chirp", draft: reply, target: &deleted, want: http.StatusConflict},
+		{name: "quote of followers-only chirp", draft: quote, target: &followersOnly, want: http.StatusConflict},
+		{name: "reply to followers-only chirp", draft: reply, target: &followersOnly, want: http.StatusOK},
+		{name: "quote", draft: quote, target: &target, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published := tt.draft
+			published.Status = chirpPublished
+
+			db := newFakeDB()
+			db.on("GetChirpByID", rows(tt.draft))
+			if tt.target != nil {
+				onVisibleChirp(db, *tt.target)
+				db.on("GetVisibleChirpsByIDs", rows(*tt.target))
+			} else {
+				db.on("GetVisibleChirpByID", fakeResult{})
+				db.on("GetVisibleChirpsByIDs", fakeResult{})
+			}
+			db.on("PublishChirp", rows(published))
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/drafts/{chirpID}/publish", cfg.handlePublishDraft, "/api/drafts/"+tt.draft.ID.String()+"/publish", testToken(t, authorID), "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK && db.ran("PublishChirp") != 0 {
+				t.Error("draft was published")
+			}
+		})
+	}
+}
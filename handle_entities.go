@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/entities"
+	"github.com/google/uuid"
+)
+
+type Entity struct {
+	Type   string     `json:"type"`
+	Text   string     `json:"text"`
+	Start  int        `json:"start"`
+	End    int        `json:"end"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+/**
+ * Find hashtags and mentions in body of chirp for json response
+ */
+func toEntities(body string) []Entity {
+	found := entities.Extract(body)
+	result := make([]Entity, len(found))
+	for i, entity := range found {
+		result[i] = Entity{
+			Type:  entity.Type,
+			Text:  entity.Text,
+			Start: entity.Start,
+			End:   entity.End,
+		}
+	}
+	return result
+}
+
+/**
+ * Save hashtags and mentions of chirp, old ones are removed. Mentions of unknown handles are skipped
+ */
+func saveEntities(ctx context.Context, db *database.Queries, chirp database.Chirp) error {
+	err := db.DeleteChirpTags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	err = db.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	found := entities.Extract(chirp.Body)
+
+	if tags := entities.Names(found, entities.TypeHashtag); len(tags) > 0 {
+		err = db.CreateChirpTags(ctx, database.CreateChirpTagsParams{
+			ChirpID: chirp.ID,
+			Tags:    tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if handles := entities.Names(found, entities.TypeMention); len(handles) > 0 {
+		err = db.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{
+			ChirpID: chirp.ID,
+			Handles: handles,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Set id of mentioned users to mention entities of chirps
+ */
+func (confg *apiConfig) withMentions(ctx context.Context, chirps []*Chirpy) error {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		for _, entity := range chirp.Entities {
+			if entity.Type == entities.TypeMention {
+				ids = append(ids, chirp.ID)
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	mentions, err := confg.db.GetMentionsByChirpIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	//chirp id -> lowercase handle -> user id
+	users := map[uuid.UUID]map[string]uuid.UUID{}
+	for _, mention := range mentions {
+		if users[mention.ChirpID] == nil {
+			users[mention.ChirpID] = map[string]uuid.UUID{}
+		}
+		users[mention.ChirpID][strings.ToLower(mention.Handle.String)] = mention.UserID
+	}
+
+	for _, chirp := range chirps {
+		for i, entity := range chirp.Entities {
+			if entity.Type != entities.TypeMention {
+				continue
+			}
+			if userID, ok := users[chirp.ID][strings.ToLower(entity.Text)]; ok {
+				chirp.Entities[i].UserID = &userID
+			}
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Handle list of chirps with hashtag, newest first
+ */
+func (confg *apiConfig) handleGetTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.TrimPrefix(r.PathValue("tag"), "#")
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing tag")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := confg.db.GetChirpsByTag(r.Context(), database.GetChirpsByTagParams{
+		ViewerID:        confg.viewerID(r),
+		Tag:             tag,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.respondWithChirpPage(w, r, chirps, limit)
+}
+
+/**
+ * Handle list of chirps which mention user, newest first
+ */
+func (confg *apiConfig) handleGetUserMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid userID")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := confg.db.GetChirpsMentioningUser(r.Context(), database.GetChirpsMentioningUserParams{
+		ViewerID:        confg.viewerID(r),
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.respondWithChirpPage(w, r, chirps, limit)
+}
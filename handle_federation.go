@@ -0,0 +1,695 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/activitypub"
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+const (
+	// Activities are small, bigger bodies are refused
+	maxInboxBytes = 256 << 10
+	// Chirps in the outbox, older ones are not served
+	outboxSize = 20
+)
+
+type RemoteActor struct {
+	ID       uuid.UUID `json:"id"`
+	URI      string    `json:"uri"`
+	Username string    `json:"username"`
+}
+
+type RemoteFollowing struct {
+	Actor      RemoteActor `json:"actor"`
+	Accepted   bool        `json:"accepted"`
+	FollowedAt time.Time   `json:"followed_at"`
+}
+
+type RemoteNote struct {
+	ID          uuid.UUID   `json:"id"`
+	URI         string      `json:"uri"`
+	URL         string      `json:"url"`
+	Content     string      `json:"content"`
+	Actor       RemoteActor `json:"actor"`
+	InReplyTo   *uuid.UUID  `json:"in_reply_to,omitempty"`
+	PublishedAt time.Time   `json:"published_at"`
+}
+
+func toRemoteActor(actor database.RemoteActor) RemoteActor {
+	return RemoteActor{ID: actor.ID, URI: actor.Uri, Username: actor.PreferredUsername}
+}
+
+func respondWithActivity(w http.ResponseWriter, contentType string, payload interface{}) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+/**
+ * Check that federation is configured. Ids of actors and notes are built from PUBLIC_URL,
+ * they must not depend on the Host header of the request
+ */
+func (cfg *apiConfig) federationEnabled(w http.ResponseWriter) bool {
+	if cfg.publicURL == "" {
+		respondWithError(w, http.StatusServiceUnavailable, "Federation is not configured")
+		return false
+	}
+	return true
+}
+
+/**
+ * Get local user who can be federated: the user must have a handle, it is the username of the actor
+ */
+func (cfg *apiConfig) federatedUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	if !cfg.federationEnabled(w) {
+		return database.User{}, false
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid userID")
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil || !user.Handle.Valid {
+		respondWithError(w, 404, "User not found")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+/**
+ * Handle WebFinger lookup of acct:handle@host, it points to the actor of the user
+ */
+func (cfg *apiConfig) handleWebFinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	handle, host, ok := strings.Cut(strings.TrimPrefix(resource, "acct:"), "@")
+	if !strings.HasPrefix(resource, "acct:") || !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid resource")
+		return
+	}
+	if !cfg.federationEnabled(w) {
+		return
+	}
+
+	base := cfg.publicURL
+	local, err := url.Parse(base)
+	if err != nil || !strings.EqualFold(host, local.Host) {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	user, err := cfg.db.GetUserByHandle(r.Context(), handle)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	respondWithActivity(w, activitypub.JRDContentType, activitypub.WebFinger{
+		Subject: "acct:" + user.Handle.String + "@" + local.Host,
+		Aliases: []string{actorURI(base, user.ID)},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorURI(base, user.ID)},
+		},
+	})
+}
+
+/**
+ * Handle actor document of the user with public key for HTTP Signatures
+ */
+func (cfg *apiConfig) handleGetActor(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := cfg.actorKey(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithActivity(w, activitypub.ContentType, toActor(cfg.publicURL, user, key))
+}
+
+/**
+ * Handle outbox of the user: Create activities of the latest public chirps
+ */
+func (cfg *apiConfig) handleGetOutbox(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	start := paging.Start(true)
+	chirps, err := cfg.db.GetChirpsPageByUserIDDesc(r.Context(), database.GetChirpsPageByUserIDDescParams{
+		UserID:          user.ID,
+		ViewerID:        uuid.Nil,
+		CursorCreatedAt: start.CreatedAt,
+		CursorID:        start.ID,
+		PageLimit:       outboxSize,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	base := cfg.publicURL
+	items := []interface{}{}
+	for _, chirp := range chirps {
+		if !isFederated(chirp) {
+			continue
+		}
+		activity, err := createActivity(base, chirp)
+		if err != nil {
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		activity.Context = nil
+		items = append(items, activity)
+	}
+
+	respondWithActivity(w, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:      activitypub.ActivityStreams,
+		ID:           actorURI(base, user.ID) + "/outbox",
+		Type:         activitypub.TypeOrdered,
+		TotalItems:   int64(len(items)),
+		OrderedItems: items,
+	})
+}
+
+/**
+ * Handle followers collection of the user, only the count of local and remote followers is public
+ */
+func (cfg *apiConfig) handleGetFollowersCollection(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	total, err := cfg.db.CountAllFollowers(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithActivity(w, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:    activitypub.ActivityStreams,
+		ID:         actorURI(cfg.publicURL, user.ID) + "/followers",
+		Type:       activitypub.TypeOrdered,
+		TotalItems: total,
+	})
+}
+
+/**
+ * Handle note of a public chirp
+ */
+func (cfg *apiConfig) handleGetNote(w http.ResponseWriter, r *http.Request) {
+	if !cfg.federationEnabled(w) {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: uuid.Nil,
+	})
+	if err != nil || !isPublic(chirp) || !isFederated(chirp) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	note := toNote(cfg.publicURL, chirp)
+	note.Context = activitypub.ActivityStreams
+	respondWithActivity(w, activitypub.ContentType, note)
+}
+
+/**
+ * Handle activity delivered to inbox of the user. Request must be signed by the actor of the activity
+ */
+func (cfg *apiConfig) handleInbox(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+	if cfg.federation == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Federation is not configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBytes+1))
+	if err != nil || len(body) > maxInboxBytes {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Activity is too large")
+		return
+	}
+
+	actor, err := cfg.verifyInboxRequest(r, body)
+	if err != nil {
+		log.Printf("inbox of %s: %v", user.ID, err)
+		respondWithError(w, 401, "Invalid signature")
+		return
+	}
+
+	var activity activitypub.Activity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.ID == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid activity")
+		return
+	}
+	if activity.Actor != actor.Uri {
+		respondWithError(w, 401, "Activity is not signed by its actor")
+		return
+	}
+
+	err = cfg.handleActivity(r.Context(), cfg.publicURL, user, actor, activity)
+	if errors.Is(err, errInvalidActivity) {
+		respondWithError(w, http.StatusBadRequest, "Invalid activity")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, nil)
+}
+
+/**
+ * Find signer of the request and verify the signature. Saved key is tried first, when it doesn't
+ * match the actor is fetched again, because the key may have been rotated
+ */
+func (cfg *apiConfig) verifyInboxRequest(r *http.Request, body []byte) (database.RemoteActor, error) {
+	sig, err := activitypub.ParseSignature(r)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	actor, err := cfg.db.GetRemoteActorByKeyID(r.Context(), sig.KeyID)
+	if err == nil && verifyActorSignature(r, body, sig, actor) == nil {
+		return actor, nil
+	}
+
+	owner, _, _ := strings.Cut(sig.KeyID, "#")
+	actor, err = cfg.fetchRemoteActor(r.Context(), owner)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	if actor.KeyID != sig.KeyID {
+		return database.RemoteActor{}, errors.New("unknown key " + sig.KeyID)
+	}
+	return actor, verifyActorSignature(r, body, sig, actor)
+}
+
+func verifyActorSignature(r *http.Request, body []byte, sig activitypub.Signature, actor database.RemoteActor) error {
+	key, err := activitypub.ParsePublicKey(actor.PublicKeyPem)
+	if err != nil {
+		return err
+	}
+	return activitypub.Verify(r, body, sig, key, time.Now())
+}
+
+/**
+ * Apply activity of remote actor to the local user. Unknown activities are accepted and ignored
+ */
+func (cfg *apiConfig) handleActivity(ctx context.Context, base string, user database.User, actor database.RemoteActor, activity activitypub.Activity) error {
+	object, err := activitypub.DecodeObject(activity.Object)
+	if err != nil {
+		return errInvalidActivity
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		if object.ID != actorURI(base, user.ID) {
+			return errInvalidActivity
+		}
+		err := cfg.db.CreateRemoteFollower(ctx, database.CreateRemoteFollowerParams{
+			UserID:    user.ID,
+			ActorID:   actor.ID,
+			FollowUri: activity.ID,
+		})
+		if err != nil {
+			return err
+		}
+		accept := activitypub.Activity{
+			Context: activitypub.ActivityStreams,
+			ID:      actorURI(base, user.ID) + "#accepts/" + uuid.NewString(),
+			Type:    activitypub.TypeAccept,
+			Actor:   actorURI(base, user.ID),
+			Object:  activity.Object,
+		}
+		go func() {
+			if err := cfg.deliver(context.Background(), user.ID, actor.Inbox, accept); err != nil {
+				log.Printf("deliver %s: %v", accept.ID, err)
+			}
+		}()
+		return nil
+
+	case activitypub.TypeUndo:
+		switch object.Type {
+		case activitypub.TypeFollow:
+			return cfg.db.DeleteRemoteFollower(ctx, database.DeleteRemoteFollowerParams{UserID: user.ID, ActorID: actor.ID})
+		case activitypub.TypeLike:
+			return cfg.db.DeleteRemoteLike(ctx, database.DeleteRemoteLikeParams{LikeUri: object.ID, ActorID: actor.ID})
+		}
+		return nil
+
+	case activitypub.TypeAccept:
+		_, err := cfg.db.AcceptRemoteFollowing(ctx, database.AcceptRemoteFollowingParams{FollowUri: object.ID, ActorID: actor.ID})
+		return err
+
+	case activitypub.TypeCreate:
+		return cfg.createRemoteNote(ctx, base, user, actor, activity.Object)
+
+	case activitypub.TypeDelete:
+		if object.ID == actor.Uri {
+			return cfg.db.DeleteRemoteActor(ctx, actor.ID)
+		}
+		return cfg.db.DeleteRemoteNote(ctx, database.DeleteRemoteNoteParams{Uri: object.ID, ActorID: actor.ID})
+
+	case activitypub.TypeLike:
+		chirpID, ok := localID(base, "/ap/chirps/", object.ID)
+		if !ok {
+			return nil
+		}
+		chirp, err := cfg.db.GetVisibleChirpByID(ctx, database.GetVisibleChirpByIDParams{ID: chirpID, ViewerID: uuid.Nil})
+		if err != nil || !isPublic(chirp) || !isFederated(chirp) {
+			return nil
+		}
+		return cfg.db.CreateRemoteLike(ctx, database.CreateRemoteLikeParams{ChirpID: chirp.ID, ActorID: actor.ID, LikeUri: activity.ID})
+	}
+	return nil
+}
+
+/**
+ * Save note of remote actor followed by the user, notes of other actors are ignored
+ */
+func (cfg *apiConfig) createRemoteNote(ctx context.Context, base string, user database.User, actor database.RemoteActor, raw json.RawMessage) error {
+	var note activitypub.Note
+	if err := json.Unmarshal(raw, &note); err != nil || note.ID == "" {
+		return errInvalidActivity
+	}
+	if note.Type != activitypub.TypeNote || note.AttributedTo != actor.Uri {
+		return nil
+	}
+
+	following, err := cfg.db.IsFollowingRemoteActor(ctx, database.IsFollowingRemoteActorParams{UserID: user.ID, ActorID: actor.ID})
+	if err != nil || !following {
+		return err
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339, note.Published)
+	if err != nil {
+		publishedAt = time.Now()
+	}
+	inReplyTo := uuid.NullUUID{}
+	if chirpID, ok := localID(base, "/ap/chirps/", note.InReplyTo); ok {
+		inReplyTo = uuid.NullUUID{UUID: chirpID, Valid: true}
+	}
+	noteURL := note.URL
+	if noteURL == "" {
+		noteURL = note.ID
+	}
+
+	params := database.CreateRemoteNoteParams{
+		Uri:         note.ID,
+		ActorID:     actor.ID,
+		Content:     activitypub.PlainText(note.Content),
+		Url:         noteURL,
+		InReplyTo:   inReplyTo,
+		PublishedAt: publishedAt.UTC(),
+	}
+	err = cfg.db.CreateRemoteNote(ctx, params)
+	if isForeignKeyViolation(err) {
+		// Replied chirp was removed meanwhile
+		params.InReplyTo = uuid.NullUUID{}
+		err = cfg.db.CreateRemoteNote(ctx, params)
+	}
+	return err
+}
+
+/**
+ * Handle follow of remote account, body is {"account": "user@host"}. The account is found with
+ * WebFinger and Follow is delivered to its inbox, it is accepted when the remote server answers
+ */
+func (cfg *apiConfig) handleFollowRemote(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	if cfg.federation == nil || cfg.publicURL == "" {
+		respondWithError(w, http.StatusServiceUnavailable, "Federation is not configured")
+		return
+	}
+
+	var params struct {
+		Account string `json:"account"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if !user.Handle.Valid {
+		respondWithError(w, http.StatusBadRequest, "Set a handle to follow remote accounts")
+		return
+	}
+
+	uri, err := cfg.federation.LookupAccount(r.Context(), params.Account)
+	if err != nil {
+		respondWithError(w, 404, "Account not found")
+		return
+	}
+	actor, err := cfg.fetchRemoteActor(r.Context(), uri)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Account can't be fetched")
+		return
+	}
+
+	object, err := json.Marshal(actor.Uri)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	follow := activitypub.Activity{
+		Context: activitypub.ActivityStreams,
+		ID:      actorURI(cfg.publicURL, userID) + "#follows/" + uuid.NewString(),
+		Type:    activitypub.TypeFollow,
+		Actor:   actorURI(cfg.publicURL, userID),
+		Object:  object,
+	}
+
+	// Saved before delivery, Accept may come before the delivery returns
+	err = cfg.db.CreateRemoteFollowing(r.Context(), database.CreateRemoteFollowingParams{
+		UserID:    userID,
+		ActorID:   actor.ID,
+		FollowUri: follow.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if err := cfg.deliver(r.Context(), userID, actor.Inbox, follow); err != nil {
+		// Following which stays after failed delivery is shown as not accepted, it can be unfollowed
+		_, err = cfg.db.DeleteRemoteFollowing(r.Context(), database.DeleteRemoteFollowingParams{UserID: userID, ActorID: actor.ID})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("remove following %s: %v", follow.ID, err)
+		}
+		respondWithError(w, http.StatusBadGateway, "Follow can't be delivered")
+		return
+	}
+
+	respondWithJSON(w, 201, RemoteFollowing{Actor: toRemoteActor(actor), FollowedAt: time.Now().UTC()})
+}
+
+/**
+ * Handle unfollow of remote actor, Undo is delivered in background
+ */
+func (cfg *apiConfig) handleUnfollowRemote(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	actorID, err := uuid.Parse(r.PathValue("actorID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid actorID")
+		return
+	}
+
+	actor, err := cfg.db.GetRemoteActorByID(r.Context(), actorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Not following")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	following, err := cfg.db.DeleteRemoteFollowing(r.Context(), database.DeleteRemoteFollowingParams{
+		UserID:  userID,
+		ActorID: actorID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Not following")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if cfg.federation != nil && cfg.publicURL != "" {
+		follow, err := json.Marshal(map[string]string{
+			"id":     following.FollowUri,
+			"type":   activitypub.TypeFollow,
+			"actor":  actorURI(cfg.publicURL, userID),
+			"object": actor.Uri,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		undo := activitypub.Activity{
+			Context: activitypub.ActivityStreams,
+			ID:      following.FollowUri + "/undo",
+			Type:    activitypub.TypeUndo,
+			Actor:   actorURI(cfg.publicURL, userID),
+			Object:  follow,
+		}
+		go func() {
+			if err := cfg.deliver(context.Background(), userID, actor.Inbox, undo); err != nil {
+				log.Printf("deliver %s: %v", undo.ID, err)
+			}
+		}()
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle remote accounts followed by the authenticated user
+ */
+func (cfg *apiConfig) handleGetRemoteFollowing(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	rows, err := cfg.db.GetRemoteFollowing(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	response := make([]RemoteFollowing, len(rows))
+	for i, row := range rows {
+		response[i] = RemoteFollowing{
+			Actor:      toRemoteActor(row.RemoteActor),
+			Accepted:   row.Accepted,
+			FollowedAt: row.FollowedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, response)
+}
+
+/**
+ * Handle notes of remote accounts followed by the authenticated user, newest first
+ */
+func (cfg *apiConfig) handleGetRemoteTimeline(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := cfg.db.GetRemoteTimeline(r.Context(), database.GetRemoteTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1].RemoteNote
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.PublishedAt, ID: last.ID})
+	}
+
+	response := make([]RemoteNote, len(rows))
+	for i, row := range rows {
+		note := row.RemoteNote
+		response[i] = RemoteNote{
+			ID:          note.ID,
+			URI:         note.Uri,
+			URL:         note.Url,
+			Content:     note.Content,
+			Actor:       RemoteActor{ID: note.ActorID, URI: row.ActorUri, Username: row.PreferredUsername},
+			PublishedAt: note.PublishedAt,
+		}
+		if note.InReplyTo.Valid {
+			response[i].InReplyTo = &note.InReplyTo.UUID
+		}
+	}
+
+	respondWithJSON(w, 200, response)
+}
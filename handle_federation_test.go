@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/activitypub"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestUnfollowRemote(t *testing.T) {
+	userID, actorID := uuid.New(), uuid.New()
+	actor := database.RemoteActor{ID: actorID, Uri: "https://remote.example/users/bob", Inbox: "https://remote.example/inbox", FetchedAt: time.Now()}
+	following := database.RemoteFollowing{UserID: userID, ActorID: actorID, FollowUri: "https://local.example/ap/users/" + userID.String() + "#follows/1", Accepted: true, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name      string
+		actor     fakeResult
+		following fakeResult
+		want      int
+	}{
+		{name: "following", actor: rows(actor), following: rows(following), want: http.StatusNoContent},
+		{name: "unknown actor", actor: fakeResult{}, want: http.StatusNotFound},
+		{name: "not following", actor: rows(actor), following: fakeResult{}, want: http.StatusNotFound},
+		{name: "actor can't be loaded", actor: fakeResult{Err: errors.New("connection reset")}, want: http.StatusInternalServerError},
+		{name: "following can't be deleted", actor: rows(actor), following: fakeResult{Err: errors.New("connection reset")}, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.on("GetRemoteActorByID", tt.actor)
+			db.on("DeleteRemoteFollowing", tt.following)
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("DELETE /api/federation/following/{actorID}", cfg.handleUnfollowRemote, "/api/federation/following/"+actorID.String(), testToken(t, userID), "")
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+/**
+ * Remote server with account bob, its inbox refuses every activity
+ */
+func newRemoteServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/webfinger":
+			json.NewEncoder(w).Encode(activitypub.WebFinger{Links: []activitypub.WebFingerLink{
+				{Rel: "self", Type: activitypub.ContentType, Href: server.URL + "/users/bob"},
+			}})
+		case "/users/bob":
+			id := server.URL + "/users/bob"
+			json.NewEncoder(w).Encode(activitypub.Actor{ID: id, Type: "Person", PreferredUsername: "bob", Inbox: server.URL + "/inbox",
+				PublicKey: activitypub.PublicKey{ID: id + "#main-key", Owner: id}})
+		default:
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFollowRemoteDeliveryFails(t *testing.T) {
+	userID := uuid.New()
+	server := newRemoteServer(t)
+	actor := database.RemoteActor{ID: uuid.New(), Uri: server.URL + "/users/bob", Inbox: server.URL + "/inbox", FetchedAt: time.Now()}
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		rollback fakeResult
+	}{
+		{name: "following removed", rollback: rows(database.RemoteFollowing{UserID: userID, ActorID: actor.ID, CreatedAt: time.Now()})},
+		{name: "following already removed", rollback: fakeResult{}},
+		{name: "following can't be removed", rollback: fakeResult{Err: errors.New("connection reset")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.on("GetUserByID", rows(database.User{ID: userID, Handle: sql.NullString{String: "alice", Valid: true}}))
+			db.on("UpsertRemoteActor", rows(actor))
+			db.on("CreateRemoteFollowing", fakeResult{Affected: 1})
+			db.on("GetActorKey", rows(database.ActorKey{UserID: userID, PublicKeyPem: publicPEM, PrivateKeyPem: privatePEM, CreatedAt: time.Now()}))
+			db.on("DeleteRemoteFollowing", tt.rollback)
+			cfg := newTestConfig(t, db)
+			cfg.federation = &activitypub.Client{HTTP: server.Client(), AllowHTTP: true}
+			cfg.publicURL = "https://chirpy.example"
+
+			host := strings.TrimPrefix(server.URL, "http://")
+			rec := serveTest("POST /api/federation/following", cfg.handleFollowRemote, "/api/federation/following", testToken(t, userID), `{"account": "bob@`+host+`"}`)
+			if rec.Code != http.StatusBadGateway {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadGateway, rec.Body)
+			}
+			if db.ran("DeleteRemoteFollowing") != 1 {
+				t.Errorf("following was removed %d times, want once", db.ran("DeleteRemoteFollowing"))
+			}
+		})
+	}
+}
+
+func TestCreateRemoteNoteReplyRemoved(t *testing.T) {
+	const base = "https://chirpy.example"
+	user := database.User{ID: uuid.New()}
+	actor := database.RemoteActor{ID: uuid.New(), Uri: "https://remote.example/users/bob"}
+	chirpID := uuid.New()
+	raw, _ := json.Marshal(activitypub.Note{ID: actor.Uri + "/notes/1", Type: activitypub.TypeNote, AttributedTo: actor.Uri,
+		Content: "<p>hi</p>", InReplyTo: base + "/ap/chirps/" + chirpID.String()})
+
+	db := newFakeDB()
+	db.on("IsFollowingRemoteActor", fakeResult{Rows: [][]driver.Value{{true}}})
+	var saved []driver.Value
+	db.onArgs("CreateRemoteNote", func(args []driver.Value) fakeResult {
+		if hasArg(args, chirpID) {
+			return fakeResult{Err: &pq.Error{Code: "23503"}}
+		}
+		saved = args
+		return fakeResult{Affected: 1}
+	})
+	cfg := newTestConfig(t, db)
+
+	if err := cfg.createRemoteNote(context.Background(), base, user, actor, raw); err != nil {
+		t.Fatal(err)
+	}
+	if db.ran("CreateRemoteNote") != 2 {
+		t.Errorf("note was saved %d times, want again without reply", db.ran("CreateRemoteNote"))
+	}
+	if !hasArg(saved, actor.Uri+"/notes/1") || !hasArg(saved, "hi") {
+		t.Errorf("saved note = %v, want the same note without reply", saved)
+	}
+}
+
+func TestWebFingerNeedsPublicURL(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		publicURL string
+		resource  string
+		want      int
+	}{
+		{name: "no public url", resource: "acct:alice@example.com", want: http.StatusServiceUnavailable},
+		{name: "host of request", publicURL: "https://chirpy.example", resource: "acct:alice@example.com", want: http.StatusNotFound},
+		{name: "host of public url", publicURL: "https://chirpy.example", resource: "acct:alice@chirpy.example", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.on("GetUserByHandle", rows(database.User{ID: userID, Handle: sql.NullString{String: "alice", Valid: true}}))
+			cfg := newTestConfig(t, db)
+			cfg.publicURL = tt.publicURL
+
+			// Request is sent to example.com, the host of httptest requests
+			rec := serveTest("GET /.well-known/webfinger", cfg.handleWebFinger, "/.well-known/webfinger?resource="+tt.resource, "", "")
+			if tt.want != http.StatusOK {
+				if rec.Code != tt.want {
+					t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				return
+			}
+
+			var got activitypub.WebFinger
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if len(got.Links) != 1 || got.Links[0].Href != tt.publicURL+"/ap/users/"+userID.String() {
+				t.Errorf("links = %v, want actor on %s", got.Links, tt.publicURL)
+			}
+		})
+	}
+}
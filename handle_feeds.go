@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/entities"
+	"github.com/St5/goboot-srv/internal/feed"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+// Chirps in every feed, feed readers poll often and only need the latest ones
+const feedSize = 50
+
+// Length of item title in runes, the rest of the body is only in content
+const feedTitleLength = 80
+
+/**
+ * Handle feed of the user: public chirps, newest first. Format is chosen by extension: .atom, .rss or .json
+ */
+func (confg *apiConfig) handleGetUserFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid userID")
+		return
+	}
+
+	user, err := confg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	start := paging.Start(true)
+	chirps, err := confg.db.GetChirpsPageByUserIDDesc(r.Context(), database.GetChirpsPageByUserIDDescParams{
+		UserID:          userID,
+		ViewerID:        uuid.Nil,
+		CursorCreatedAt: start.CreatedAt,
+		CursorID:        start.ID,
+		PageLimit:       feedSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	name := authorName(user.ID, user.Handle.String)
+	confg.respondWithFeed(w, r, feed.Feed{
+		Title:       "Chirps of " + name,
+		Description: "Latest chirps of " + name + " on Chirpy",
+		Link:        confg.baseURL(r) + "/api/chirps?author_id=" + userID.String(),
+		Updated:     user.CreatedAt,
+	}, chirps, database.GetLastChirpDeletionParams{UserID: uuid.NullUUID{UUID: userID, Valid: true}})
+}
+
+/**
+ * Handle feed of all public chirps, newest first
+ */
+func (confg *apiConfig) handleGetGlobalFeed(w http.ResponseWriter, r *http.Request) {
+	start := paging.Start(true)
+	chirps, err := confg.db.GetChirpsPageDesc(r.Context(), database.GetChirpsPageDescParams{
+		ViewerID:        uuid.Nil,
+		CursorCreatedAt: start.CreatedAt,
+		CursorID:        start.ID,
+		PageLimit:       feedSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.respondWithFeed(w, r, feed.Feed{
+		Title:       "Chirpy",
+		Description: "Latest public chirps on Chirpy",
+		Link:        confg.baseURL(r) + "/api/chirps?sort=desc",
+	}, chirps, database.GetLastChirpDeletionParams{})
+}
+
+/**
+ * Handle feed of public chirps with the hashtag, newest first
+ */
+func (confg *apiConfig) handleGetTagFeed(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing tag")
+		return
+	}
+
+	start := paging.Start(true)
+	chirps, err := confg.db.GetChirpsByTag(r.Context(), database.GetChirpsByTagParams{
+		Tag:             tag,
+		ViewerID:        uuid.Nil,
+		CursorCreatedAt: start.CreatedAt,
+		CursorID:        start.ID,
+		PageLimit:       feedSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.respondWithFeed(w, r, feed.Feed{
+		Title:       "#" + tag + " on Chirpy",
+		Description: "Latest public chirps with #" + tag,
+		Link:        confg.baseURL(r) + "/api/tags/" + tag + "/chirps",
+	}, chirps, database.GetLastChirpDeletionParams{Tag: sql.NullString{String: tag, Valid: true}})
+}
+
+/**
+ * Render chirps into the feed in format from extension of the path. Updated of the feed is the latest
+ * change of its chirps or delete of a chirp in scope of the feed, so a removed item changes it too.
+ * ETag is a hash of the body, conditional requests get 304 when nothing changed
+ */
+func (confg *apiConfig) respondWithFeed(w http.ResponseWriter, r *http.Request, f feed.Feed, chirps []database.Chirp, scope database.GetLastChirpDeletionParams) {
+	render, contentType := feed.Atom, feed.ContentTypeAtom
+	switch path.Ext(r.URL.Path) {
+	case ".rss":
+		render, contentType = feed.RSS, feed.ContentTypeRSS
+	case ".json":
+		render, contentType = feed.JSON, feed.ContentTypeJSON
+	}
+
+	items, err := confg.feedItems(r.Context(), confg.baseURL(r), chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	f.Items = items
+	f.FeedURL = confg.baseURL(r) + r.URL.Path
+	for _, item := range items {
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+	}
+
+	deletedAt, err := confg.db.GetLastChirpDeletion(r.Context(), scope)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if deletedAt.Valid && deletedAt.Time.After(f.Updated) {
+		f.Updated = deletedAt.Time
+	}
+
+	body, err := render(f)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(body))
+}
+
+func (confg *apiConfig) feedItems(ctx context.Context, baseURL string, chirps []database.Chirp) ([]feed.Item, error) {
+	chirpies := make([]Chirpy, len(chirps))
+	pointers := make([]*Chirpy, len(chirps))
+	authorIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpies[i] = toChirpy(chirp)
+		pointers[i] = &chirpies[i]
+		authorIDs[i] = chirp.UserID
+	}
+	if err := confg.withAttachments(ctx, pointers); err != nil {
+		return nil, err
+	}
+
+	users, err := confg.db.GetUserHandlesByIDs(ctx, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[uuid.UUID]string, len(users))
+	for _, user := range users {
+		authors[user.ID] = authorName(user.ID, user.Handle.String)
+	}
+
+	items := make([]feed.Item, len(chirps))
+	for i, chirp := range chirps {
+		url := baseURL + "/api/chirps/" + chirp.ID.String()
+		content := chirp.Body
+		if chirp.RechirpOf.Valid {
+			content = "Rechirp of " + baseURL + "/api/chirps/" + chirp.RechirpOf.UUID.String()
+		} else if chirp.QuoteOf.Valid {
+			content += "\n\nQuote of " + baseURL + "/api/chirps/" + chirp.QuoteOf.UUID.String()
+		}
+
+		items[i] = feed.Item{
+			ID:        url,
+			URL:       url,
+			Title:     feedTitle(content),
+			Content:   content,
+			Author:    authors[chirp.UserID],
+			Published: chirp.CreatedAt,
+			Updated:   chirp.UpdatedAt,
+			Tags:      entities.Names(entities.Extract(chirp.Body), entities.TypeHashtag),
+		}
+		for _, attachment := range chirpies[i].Attachments {
+			items[i].Attachments = append(items[i].Attachments, feed.Attachment{
+				URL:         baseURL + attachment.URL,
+				ContentType: attachment.ContentType,
+				Size:        attachment.Size,
+			})
+		}
+	}
+	return items, nil
+}
+
+/**
+ * Public name of the author, users without handle are shown by id. E-mail is never shown
+ */
+func authorName(userID uuid.UUID, handle string) string {
+	if handle != "" {
+		return "@" + handle
+	}
+	return "user " + userID.String()
+}
+
+/**
+ * First line of the content, cut to feedTitleLength runes
+ */
+func feedTitle(content string) string {
+	title, _, _ := strings.Cut(content, "\n")
+	runes := []rune(title)
+	if len(runes) > feedTitleLength {
+		return string(runes[:feedTitleLength-1]) + "…"
+	}
+	return title
+}
+
+/**
+ * Absolute URL of the server: PUBLIC_URL when it is set, otherwise scheme and host of the request.
+ * Only links in feeds use the request, ActivityPub ids are built from PUBLIC_URL
+ */
+func (confg *apiConfig) baseURL(r *http.Request) string {
+	if confg.publicURL != "" {
+		return confg.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
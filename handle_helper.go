@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string) {
@@ -32,4 +41,95 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}){
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	w.Write(dat)
-}
\ No newline at end of file
+}
+
+/**
+ * Set Link header with url of the next page, other query parameters are kept
+ */
+func setNextPageLink(w http.ResponseWriter, r *http.Request, cursor paging.Cursor) {
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", cursor.Encode())
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+}
+
+/**
+ * Get id of user from bearer token when it is present and valid, otherwise uuid.Nil
+ */
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+/**
+ * Run queries in one transaction, it is committed when fn returns no error
+ */
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(cfg.db.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/**
+ * Check if database error is violation of unique constraint
+ */
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+/**
+ * Check if database error is violation of foreign key constraint
+ */
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+/**
+ * Name of constraint or unique index violated by database error, empty for other errors
+ */
+func violatedConstraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	return ""
+}
+
+/**
+ * Read limit and cursor query parameters. Without cursor the page starts from the first row
+ */
+func parsePage(r *http.Request, desc bool) (int, paging.Cursor, error) {
+	limit, err := paging.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		return 0, paging.Cursor{}, err
+	}
+
+	cursor := paging.Start(desc)
+	if cursorParam := r.URL.Query().Get("cursor"); cursorParam != "" {
+		cursor, err = paging.Decode(cursorParam)
+		if err != nil {
+			return 0, paging.Cursor{}, err
+		}
+	}
+
+	return limit, cursor, nil
+}
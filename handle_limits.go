@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/textlen"
+	"github.com/google/uuid"
+)
+
+const (
+	planFree       = "free"
+	planChirpyRed  = "chirpy_red"
+	freeChirpLimit = 140
+	redChirpLimit  = 500
+	freePinLimit   = 1
+	redPinLimit    = 3
+	// Hard limits on raw size, grapheme cluster of emoji can take tens of bytes
+	maxBytesPerChar = 32
+	maxURLLength    = 1024
+)
+
+/**
+ * Limits of user plan. Length of chirp is counted in user-perceived characters,
+ * every URL counts as URLLength characters. Size of body in bytes and of every URL is limited too
+ */
+type Limits struct {
+	Plan            string `json:"plan"`
+	MaxChirpLength  int    `json:"max_chirp_length"`
+	MaxChirpBytes   int    `json:"max_chirp_bytes"`
+	URLLength       int    `json:"url_length"`
+	MaxURLLength    int    `json:"max_url_length"`
+	MaxPinnedChirps int    `json:"max_pinned_chirps"`
+}
+
+/**
+ * Limits of plan of the user, Chirpy Red members get longer chirps and more pinned chirps
+ */
+func limitsForUser(user database.User) Limits {
+	if user.IsChirpyRed.Valid && user.IsChirpyRed.Bool {
+		return Limits{
+			Plan:            planChirpyRed,
+			MaxChirpLength:  redChirpLimit,
+			MaxChirpBytes:   redChirpLimit * maxBytesPerChar,
+			URLLength:       textlen.DefaultURLWeight,
+			MaxURLLength:    maxURLLength,
+			MaxPinnedChirps: redPinLimit,
+		}
+	}
+	return Limits{
+		Plan:            planFree,
+		MaxChirpLength:  freeChirpLimit,
+		MaxChirpBytes:   freeChirpLimit * maxBytesPerChar,
+		URLLength:       textlen.DefaultURLWeight,
+		MaxURLLength:    maxURLLength,
+		MaxPinnedChirps: freePinLimit,
+	}
+}
+
+/**
+ * Load user and get limits of the plan, anonymous users get free plan
+ */
+func (cfg *apiConfig) userLimits(ctx context.Context, userID uuid.UUID) (Limits, error) {
+	if userID == uuid.Nil {
+		return limitsForUser(database.User{}), nil
+	}
+
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return Limits{}, err
+	}
+	return limitsForUser(user), nil
+}
+
+/**
+ * Chirp body over limits of user plan, e.g. chirp or URL which is too long
+ */
+type limitError struct {
+	subject string
+	max     int
+	unit    string
+}
+
+func (err *limitError) Error() string {
+	return fmt.Sprintf("%s is too long, max %d %s", err.subject, err.max, err.unit)
+}
+
+/**
+ * Message for the user which starts with capital letter
+ */
+func (err *limitError) message() string {
+	return strings.ToUpper(err.subject[:1]) + err.Error()[1:]
+}
+
+/**
+ * Check chirp body with limits of user plan, the error is *limitError.
+ * Raw size is checked first, so long URLs can't hide in the weighted count
+ */
+func (limits Limits) check(body string) error {
+	if len(body) > limits.MaxChirpBytes {
+		return &limitError{subject: "chirp", max: limits.MaxChirpBytes, unit: "bytes"}
+	}
+	for _, url := range textlen.URLs(body) {
+		if len(url) > limits.MaxURLLength {
+			return &limitError{subject: "URL", max: limits.MaxURLLength, unit: "characters"}
+		}
+	}
+	if textlen.Length(body, limits.URLLength) > limits.MaxChirpLength {
+		return &limitError{subject: "chirp", max: limits.MaxChirpLength, unit: "characters"}
+	}
+	return nil
+}
+
+/**
+ * Respond with error of check, false when the error is written
+ */
+func respondWithLimitError(w http.ResponseWriter, err error) bool {
+	var limitErr *limitError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &limitErr):
+		respondWithError(w, 400, limitErr.message())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	}
+	return false
+}
+
+/**
+ * Handle limits of the authenticated user, without token limits of free plan
+ */
+func (cfg *apiConfig) handleGetLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := cfg.userLimits(r.Context(), cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 200, limits)
+}
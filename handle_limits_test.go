@@ -0,0 +1,52 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/St5/goboot-srv/internal/database"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	free := limitsForUser(database.User{})
+	red := limitsForUser(database.User{IsChirpyRed: sql.NullBool{Bool: true, Valid: true}})
+
+	tests := []struct {
+		name        string
+		limits      Limits
+		body        string
+		wantErr     string
+		wantMessage string
+	}{
+		{name: "fits free plan", limits: free, body: strings.Repeat("a", 140)},
+		{name: "fits red plan", limits: red, body: strings.Repeat("a", 500)},
+		{name: "too many characters", limits: free, body: strings.Repeat("a", 141),
+			wantErr: "chirp is too long, max 140 characters", wantMessage: "Chirp is too long, max 140 characters"},
+		{name: "too many bytes", limits: free, body: strings.Repeat("👍🏽", free.MaxChirpBytes),
+			wantErr: "chirp is too long, max 4480 bytes", wantMessage: "Chirp is too long, max 4480 bytes"},
+		{name: "too long url", limits: red, body: "https://example.com/" + strings.Repeat("a", maxURLLength),
+			wantErr: "URL is too long, max 1024 characters", wantMessage: "URL is too long, max 1024 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.check(tt.body)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("check() = %v, want no error", err)
+				}
+				return
+			}
+
+			var limitErr *limitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("check() = %v, want *limitError", err)
+			}
+			if err.Error() != tt.wantErr || limitErr.message() != tt.wantMessage {
+				t.Errorf("check() = %q with message %q, want %q with message %q", err, limitErr.message(), tt.wantErr, tt.wantMessage)
+			}
+		})
+	}
+}
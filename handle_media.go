@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	// Max number of media attached to one chirp
+	maxAttachments = 4
+	// Default max size of uploaded file, MEDIA_MAX_BYTES overrides it
+	defaultMaxUploadBytes = 5 << 20
+	// Url prefix of served media
+	mediaPath = "/media/"
+	// Time to attach uploaded media to a chirp or use it as avatar, then purger removes it
+	unattachedMediaRetention = 24 * time.Hour
+)
+
+type Attachment struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	Size         int64     `json:"size"`
+}
+
+func toAttachment(medium database.Medium) Attachment {
+	return Attachment{
+		ID:           medium.ID,
+		URL:          mediaPath + medium.BlobKey,
+		ThumbnailURL: mediaPath + medium.ThumbnailKey,
+		ContentType:  medium.ContentType,
+		Width:        medium.Width,
+		Height:       medium.Height,
+		Size:         medium.Size,
+	}
+}
+
+/**
+ * Create blob store from env: MEDIA_STORE=s3 uses S3 compatible storage, otherwise files in MEDIA_DIR
+ */
+func newBlobStore() media.BlobStore {
+	if os.Getenv("MEDIA_STORE") == "s3" {
+		return media.S3Store{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+	}
+
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = "./media"
+	}
+	return media.LocalStore{Dir: dir}
+}
+
+/**
+ * Parse max size of upload, default is 5 MiB
+ */
+func parseMaxUploadBytes(value string) int64 {
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		return defaultMaxUploadBytes
+	}
+	return size
+}
+
+/**
+ * Handle upload of image in multipart field "file". Uploaded media is attached to chirp by id later
+ */
+func (cfg *apiConfig) handleUploadMedia(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Some room for multipart headers
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.maxUploadBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Missing file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, cfg.maxUploadBytes+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid file")
+		return
+	}
+	if int64(len(data)) > cfg.maxUploadBytes {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	}
+
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid image")
+		return
+	}
+
+	name := uuid.New().String()
+	blobKey := name + img.Ext
+	thumbnailKey := name + "_thumb" + img.ThumbnailExt
+
+	err = cfg.blobs.Put(r.Context(), blobKey, img.ContentType, bytes.NewReader(img.Data))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = cfg.blobs.Put(r.Context(), thumbnailKey, img.ThumbnailType, bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		cfg.blobs.Delete(r.Context(), blobKey)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	medium, err := cfg.db.CreateMedia(r.Context(), database.CreateMediaParams{
+		UserID:       userID,
+		ContentType:  img.ContentType,
+		Size:         int64(len(img.Data)),
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		BlobKey:      blobKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		cfg.blobs.Delete(r.Context(), blobKey)
+		cfg.blobs.Delete(r.Context(), thumbnailKey)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, toAttachment(medium))
+}
+
+/**
+ * Check that media can be attached to a new chirp: uploaded by the user, not attached yet and not an avatar
+ */
+func (cfg *apiConfig) validateMedia(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (string, bool) {
+	if len(ids) > maxAttachments {
+		return "Chirp can have up to " + strconv.Itoa(maxAttachments) + " attachments", false
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		medium, err := cfg.db.GetMediaByID(ctx, id)
+		if err != nil || medium.UserID != userID || medium.ChirpID.Valid || medium.IsAvatar || seen[id] {
+			return "Invalid media id " + id.String(), false
+		}
+		seen[id] = true
+	}
+	return "", true
+}
+
+/**
+ * Attach uploaded media to chirp in order of ids
+ */
+func attachMedia(ctx context.Context, db *database.Queries, chirp database.Chirp, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	count, err := db.AttachMedia(ctx, database.AttachMediaParams{
+		ChirpID: chirp.ID,
+		Ids:     ids,
+		UserID:  chirp.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if count != int64(len(ids)) {
+		return errors.New("media is already attached")
+	}
+	return nil
+}
+
+/**
+ * Remove files of media which rows are already deleted.
+ * Files are removed after rows, orphan file is better than broken attachment
+ */
+func (cfg *apiConfig) deleteMediaFiles(ctx context.Context, removed []database.Medium) {
+	for _, medium := range removed {
+		cfg.blobs.Delete(ctx, medium.BlobKey)
+		cfg.blobs.Delete(ctx, medium.ThumbnailKey)
+	}
+}
+
+/**
+ * Load attachments of chirps, deleted chirps have no attachments
+ */
+func (cfg *apiConfig) withAttachments(ctx context.Context, chirps []*Chirpy) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		if !chirp.Deleted {
+			ids = append(ids, chirp.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := cfg.db.GetMediaByChirpIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	attachments := map[uuid.UUID][]Attachment{}
+	for _, medium := range rows {
+		attachments[medium.ChirpID.UUID] = append(attachments[medium.ChirpID.UUID], toAttachment(medium))
+	}
+
+	for _, chirp := range chirps {
+		if chirpAttachments, ok := attachments[chirp.ID]; ok {
+			chirp.Attachments = chirpAttachments
+		}
+	}
+	return nil
+}
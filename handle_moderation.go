@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/moderation"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+type ModerationWord struct {
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FlaggedChirp struct {
+	Chirp     Chirpy    `json:"chirp"`
+	Reasons   []string  `json:"reasons"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+/**
+ * Words managed by admins in moderation_words table
+ */
+type dbWords struct {
+	db *database.Queries
+}
+
+func (source dbWords) Words(ctx context.Context) ([]moderation.Word, error) {
+	rows, err := source.db.GetModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]moderation.Word, len(rows))
+	for i, row := range rows {
+		words[i] = moderation.Word{Text: row.Word, Action: moderation.Action(row.Action)}
+	}
+	return words, nil
+}
+
+/**
+ * Build chain of moderation filters: words from database, words from file and regex rules from file.
+ * Files are optional
+ */
+func newModerator(dbSource moderation.WordSource, wordsFile, rulesFile string) (moderation.Moderator, error) {
+	chain := moderation.Chain{moderation.WordFilter{Source: dbSource}}
+
+	if wordsFile != "" {
+		fileSource := moderation.FileWords{Path: wordsFile}
+		// Check the file on start, it is read again on every check to pick up changes
+		_, err := fileSource.Words(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, moderation.WordFilter{Source: fileSource})
+	}
+
+	if rulesFile != "" {
+		filters, err := moderation.LoadRegexFilters(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, filter := range filters {
+			chain = append(chain, filter)
+		}
+	}
+
+	return chain, nil
+}
+
+/**
+ * Validate message with moderation filters. Bad words are replaced with ****,
+ * the result action tells if message must be rejected or flagged for review
+ */
+func (confg *apiConfig) validateMsg(ctx context.Context, msg string) (moderation.Result, error) {
+	return confg.moderator.Moderate(ctx, msg)
+}
+
+/**
+ * Save chirp for review by admins when moderation flagged it
+ */
+func flagChirp(ctx context.Context, db *database.Queries, chirpID uuid.UUID, result moderation.Result) error {
+	if result.Action != moderation.ActionFlag {
+		return nil
+	}
+
+	return db.FlagChirp(ctx, database.FlagChirpParams{
+		ChirpID: chirpID,
+		Reasons: strings.Join(result.Reasons, "\n"),
+	})
+}
+
+/**
+ * Update flag of edited chirp: it is flagged again with new reasons,
+ * or the earlier flag is removed when the edit has no flagged text anymore
+ */
+func reflagChirp(ctx context.Context, db *database.Queries, chirpID uuid.UUID, result moderation.Result) error {
+	if result.Action != moderation.ActionFlag {
+		_, err := db.DeleteChirpFlag(ctx, chirpID)
+		return err
+	}
+	return flagChirp(ctx, db, chirpID, result)
+}
+
+/**
+ * Handle list of moderation words
+ */
+func (cfg *apiConfig) handleGetModerationWords(w http.ResponseWriter, r *http.Request) {
+	words, err := cfg.db.GetModerationWords(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	wordsResponse := make([]ModerationWord, len(words))
+	for i, word := range words {
+		wordsResponse[i] = ModerationWord{
+			Word:      word.Word,
+			Action:    word.Action,
+			CreatedAt: word.CreatedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, wordsResponse)
+}
+
+/**
+ * Handle add moderation word or change action of existing one
+ */
+func (cfg *apiConfig) handleCreateModerationWord(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Word   string `json:"word"`
+		Action string `json:"action"`
+	}
+
+	req := request{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	// Filter matches whole words of letters and digits, words with punctuation would never match
+	word := strings.ToLower(strings.TrimSpace(req.Word))
+	if !moderation.IsWord(word) {
+		respondWithError(w, 400, "Word must be a single word of letters and digits")
+		return
+	}
+
+	if req.Action == "" {
+		req.Action = string(moderation.ActionReplace)
+	}
+	if !moderation.ValidAction(moderation.Action(req.Action)) {
+		respondWithError(w, 400, "Action must be replace, flag or reject")
+		return
+	}
+
+	wordDb, err := cfg.db.CreateModerationWord(r.Context(), database.CreateModerationWordParams{
+		Word:   word,
+		Action: req.Action,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.moderationWords.Invalidate()
+
+	respondWithJSON(w, 201, ModerationWord{
+		Word:      wordDb.Word,
+		Action:    wordDb.Action,
+		CreatedAt: wordDb.CreatedAt,
+	})
+}
+
+/**
+ * Handle remove moderation word
+ */
+func (cfg *apiConfig) handleDeleteModerationWord(w http.ResponseWriter, r *http.Request) {
+	word := strings.ToLower(r.PathValue("word"))
+
+	count, err := cfg.db.DeleteModerationWord(r.Context(), word)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if count == 0 {
+		respondWithError(w, 404, "Word not found")
+		return
+	}
+	cfg.moderationWords.Invalidate()
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle list of chirps flagged for review, latest first
+ */
+func (cfg *apiConfig) handleGetFlaggedChirps(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	flags, err := cfg.db.GetFlaggedChirps(r.Context(), database.GetFlaggedChirpsParams{
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if len(flags) > limit {
+		flags = flags[:limit]
+		last := flags[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.FlaggedAt, ID: last.Chirp.ID})
+	}
+
+	flagsResponse := make([]FlaggedChirp, len(flags))
+	for i, flag := range flags {
+		flagsResponse[i] = FlaggedChirp{
+			Chirp:     toChirpy(flag.Chirp),
+			Reasons:   strings.Split(flag.Reasons, "\n"),
+			FlaggedAt: flag.FlaggedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, flagsResponse)
+}
+
+/**
+ * Handle dismiss flag of chirp after review
+ */
+func (cfg *apiConfig) handleDeleteChirpFlag(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirpID")
+		return
+	}
+
+	count, err := cfg.db.DeleteChirpFlag(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if count == 0 {
+		respondWithError(w, 404, "Flag not found")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
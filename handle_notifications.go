@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Read      bool       `json:"read"`
+}
+
+type UnreadNotifications struct {
+	UnreadCount int64            `json:"unread_count"`
+	ByType      map[string]int64 `json:"by_type"`
+}
+
+func toNotification(notification database.Notification) Notification {
+	result := Notification{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		ActorID:   notification.ActorID,
+		CreatedAt: notification.CreatedAt,
+		Read:      notification.ReadAt.Valid,
+	}
+	if notification.ChirpID.Valid {
+		result.ChirpID = &notification.ChirpID.UUID
+	}
+	return result
+}
+
+/**
+ * Handle notifications of authenticated user, newest first. With ?unread=true only unread ones
+ */
+func (cfg *apiConfig) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	notifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      r.URL.Query().Get("unread") == "true",
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	response := make([]Notification, len(notifications))
+	for i, notification := range notifications {
+		response[i] = toNotification(notification)
+	}
+
+	respondWithJSON(w, 200, response)
+}
+
+/**
+ * Handle count of unread notifications of authenticated user, total and by type
+ */
+func (cfg *apiConfig) handleGetUnreadNotifications(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	counts, err := cfg.db.GetUnreadNotificationCounts(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	response := UnreadNotifications{ByType: map[string]int64{}}
+	for _, count := range counts {
+		response.UnreadCount += count.Unread
+		response.ByType[count.Type] = count.Unread
+	}
+
+	respondWithJSON(w, 200, response)
+}
+
+/**
+ * Handle mark notification of authenticated user as read
+ */
+func (cfg *apiConfig) handleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid notificationID")
+		return
+	}
+
+	updated, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, 404, "Notification not found")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle mark all notifications of authenticated user as read
+ */
+func (cfg *apiConfig) handleMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	err = cfg.db.MarkAllNotificationsRead(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle notification preferences of authenticated user: every type with enabled flag
+ */
+func (cfg *apiConfig) handleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	cfg.respondWithNotificationPreferences(w, r, userID)
+}
+
+/**
+ * Handle update notification preferences, body is map of type to enabled flag. Types which are
+ * not in the body are not changed
+ */
+func (cfg *apiConfig) handleUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	var preferences map[string]bool
+	err = json.NewDecoder(r.Body).Decode(&preferences)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	for notificationType := range preferences {
+		if !containe(notificationTypes, notificationType) {
+			respondWithError(w, 400, "Unknown notification type "+notificationType)
+			return
+		}
+	}
+
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		for notificationType, enabled := range preferences {
+			err := q.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+				UserID:  userID,
+				Type:    notificationType,
+				Enabled: enabled,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	cfg.respondWithNotificationPreferences(w, r, userID)
+}
+
+func (cfg *apiConfig) respondWithNotificationPreferences(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	saved, err := cfg.db.GetNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	preferences := make(map[string]bool, len(notificationTypes))
+	for _, notificationType := range notificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range saved {
+		preferences[preference.Type] = preference.Enabled
+	}
+
+	respondWithJSON(w, 200, preferences)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestGetNotifications(t *testing.T) {
+	userID := uuid.New()
+	read := database.Notification{ID: uuid.New(), UserID: userID, Type: notificationReply, ActorID: uuid.New(),
+		CreatedAt: time.Now().Add(-time.Hour), ReadAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	unread := database.Notification{ID: uuid.New(), UserID: userID, Type: notificationReply, ActorID: uuid.New(), CreatedAt: time.Now()}
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		target string
+		want   []uuid.UUID
+	}{
+		{name: "all", userID: userID, target: "/api/notifications", want: []uuid.UUID{unread.ID, read.ID}},
+		{name: "unread", userID: userID, target: "/api/notifications?unread=true", want: []uuid.UUID{unread.ID}},
+		{name: "other user", userID: uuid.New(), target: "/api/notifications"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			// Notifications of the user, newest first
+			db.onArgs("GetNotifications", func(args []driver.Value) fakeResult {
+				switch {
+				case !hasArg(args, userID):
+					return fakeResult{}
+				case hasArg(args, true):
+					return rows(unread)
+				}
+				return rows(unread, read)
+			})
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("GET /api/notifications", cfg.handleGetNotifications, tt.target, testToken(t, tt.userID), "")
+			var got []Notification
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("notifications = %+v, want %v", got, tt.want)
+			}
+			for i, id := range tt.want {
+				if got[i].ID != id {
+					t.Errorf("notification %d = %s, want %s", i, got[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	userID, notificationID := uuid.New(), uuid.New()
+	target := "/api/notifications/" + notificationID.String() + "/read"
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		target string
+		want   int
+	}{
+		{name: "invalid notificationID", userID: userID, target: "/api/notifications/abc/read", want: http.StatusBadRequest},
+		{name: "notification of other user", userID: uuid.New(), target: target, want: http.StatusNotFound},
+		{name: "own notification", userID: userID, target: target, want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			// Only the owner updates the notification
+			db.onArgs("MarkNotificationRead", func(args []driver.Value) fakeResult {
+				if hasArg(args, notificationID) && hasArg(args, userID) {
+					return fakeResult{Affected: 1}
+				}
+				return fakeResult{}
+			})
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/notifications/{notificationID}/read", cfg.handleMarkNotificationRead, tt.target, testToken(t, tt.userID), "")
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "unknown type", body: `{"` + notificationReply + `": false, "spam": true}`, want: http.StatusBadRequest},
+		{name: "known type", body: `{"` + notificationReply + `": false}`, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			db := newFakeDB()
+			db.on("SetNotificationPreference", fakeResult{Affected: 1})
+			db.on("GetNotificationPreferences", rows(database.NotificationPreference{UserID: userID, Type: notificationReply, Enabled: false}))
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("PUT /api/notifications/preferences", cfg.handleUpdateNotificationPreferences,
+				"/api/notifications/preferences", testToken(t, userID), tt.body)
+			if tt.want != http.StatusOK {
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				// Nothing is saved when one type is unknown
+				if db.ran("SetNotificationPreference") != 0 {
+					t.Error("preferences were saved with unknown type")
+				}
+				return
+			}
+
+			// Types without saved preference are enabled
+			var got map[string]bool
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if got[notificationReply] || !got[notificationFollow] {
+				t.Errorf("preferences = %v, want only %s disabled", got, notificationReply)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+var errPinLimit = errors.New("pin limit reached")
+
+/**
+ * Handle pin of own chirp. Number of pinned chirps is limited by plan of the user
+ */
+func (confg *apiConfig) handlePinChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil || !canView(chirp, userID) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+	if !isPublic(chirp) {
+		respondWithError(w, http.StatusBadRequest, "Only published chirps can be pinned")
+		return
+	}
+
+	limits, err := confg.userLimits(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// User row is locked, so concurrent pins can't go over the limit
+	err = confg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.LockUserByID(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		pinned, err := q.PinChirp(r.Context(), database.PinChirpParams{
+			UserID:  userID,
+			ChirpID: chirp.ID,
+		})
+		if err != nil || pinned == 0 {
+			return err
+		}
+
+		count, err := q.CountPinnedChirps(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		if count > int64(limits.MaxPinnedChirps) {
+			return errPinLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errPinLimit) {
+		respondWithError(w, http.StatusConflict, "Pin limit reached")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+/**
+ * Handle unpin of own chirp
+ */
+func (confg *apiConfig) handleUnpinChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	err = confg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+/**
+ * Load pinned chirps of author which are shown first on author listing.
+ * Pins over the limit of current plan (e.g. after Chirpy Red has ended) are not shown
+ */
+func (confg *apiConfig) pinnedChirps(ctx context.Context, authorID, viewerID uuid.UUID) ([]database.Chirp, error) {
+	limits, err := confg.userLimits(ctx, authorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return confg.db.GetPinnedChirpsByUserID(ctx, database.GetPinnedChirpsByUserIDParams{
+		UserID:   authorID,
+		ViewerID: viewerID,
+		PinLimit: int32(limits.MaxPinnedChirps),
+	})
+}
+
+/**
+ * Mark chirps pinned by their authors
+ */
+func (confg *apiConfig) withPins(ctx context.Context, chirps []*Chirpy) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	pinned, err := confg.db.GetPinnedChirpIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[uuid.UUID]bool, len(pinned))
+	for _, id := range pinned {
+		set[id] = true
+	}
+	for _, chirp := range chirps {
+		chirp.Pinned = set[chirp.ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPinChirp(t *testing.T) {
+	userID, otherID, chirpID := uuid.New(), uuid.New(), uuid.New()
+	target := "/api/chirps/" + chirpID.String() + "/pin"
+	own := database.Chirp{ID: chirpID, UserID: userID, Status: chirpPublished}
+	ownDraft := database.Chirp{ID: chirpID, UserID: userID, Status: chirpDraft}
+	ownDeleted := own
+	ownDeleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	other := database.Chirp{ID: chirpID, UserID: otherID, Status: chirpPublished}
+	otherDraft := database.Chirp{ID: chirpID, UserID: otherID, Status: chirpDraft}
+
+	tests := []struct {
+		name    string
+		target  string
+		chirp   *database.Chirp
+		red     bool
+		pinned  int64
+		want    int
+		wantPin bool
+	}{
+		{name: "invalid chirpID", target: "/api/chirps/abc/pin", want: http.StatusBadRequest},
+		{name: "unknown chirp", target: target, want: http.StatusNotFound},
+		{name: "deleted own chirp", target: target, chirp: &ownDeleted, want: http.StatusNotFound},
+		{name: "draft of other user", target: target, chirp: &otherDraft, want: http.StatusNotFound},
+		{name: "chirp of other user", target: target, chirp: &other, want: http.StatusForbidden},
+		{name: "own draft", target: target, chirp: &ownDraft, want: http.StatusBadRequest},
+		{name: "over free limit", target: target, chirp: &own, pinned: freePinLimit + 1, want: http.StatusConflict, wantPin: true},
+		{name: "free limit", target: target, chirp: &own, pinned: freePinLimit, want: http.StatusNoContent, wantPin: true},
+		{name: "red limit", target: target, chirp: &own, red: true, pinned: redPinLimit, want: http.StatusNoContent, wantPin: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.chirp != nil {
+				onVisibleChirp(db, *tt.chirp)
+			} else {
+				db.on("GetVisibleChirpByID", fakeResult{})
+			}
+			db.on("GetUserByID", rows(database.User{ID: userID, IsChirpyRed: sql.NullBool{Bool: tt.red, Valid: true}}))
+			db.on("LockUserByID", fakeResult{})
+			db.on("PinChirp", fakeResult{Affected: 1})
+			db.on("CountPinnedChirps", fakeResult{Rows: [][]driver.Value{{tt.pinned}}})
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/chirps/{chirpID}/pin", cfg.handlePinChirp, tt.target, testToken(t, userID), "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if pinned := db.ran("PinChirp") > 0; pinned != tt.wantPin {
+				t.Errorf("pin saved = %v, want %v", pinned, tt.wantPin)
+			}
+		})
+	}
+}
+
+func TestUnpinChirpInvalidID(t *testing.T) {
+	cfg := newTestConfig(t, newFakeDB())
+
+	rec := serveTest("DELETE /api/chirps/{chirpID}/pin", cfg.handleUnpinChirp, "/api/chirps/abc/pin", testToken(t, uuid.New()), "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
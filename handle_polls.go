@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 100
+	// Poll can be open at most this time after the chirp is published
+	maxPollDuration = 7 * 24 * time.Hour
+)
+
+type PollRequest struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type Poll struct {
+	Options    []PollOption `json:"options"`
+	TotalVotes int64        `json:"total_votes"`
+	ClosesAt   time.Time    `json:"closes_at"`
+	Closed     bool         `json:"closed"`
+	Voted      bool         `json:"voted"`
+	ViewerVote *int32       `json:"viewer_vote,omitempty"`
+}
+
+type PollOption struct {
+	Text  string `json:"text"`
+	Votes int64  `json:"votes"`
+}
+
+/**
+ * Check options and closing time of poll. Poll of scheduled chirp is counted from publish_at
+ */
+func validatePoll(poll *PollRequest, publishAt time.Time) (string, bool) {
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return "Poll must have from 2 to 4 options", false
+	}
+
+	seen := map[string]bool{}
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxPollOptionLength {
+			return "Poll option must have from 1 to 100 characters", false
+		}
+		if seen[strings.ToLower(option)] {
+			return "Poll options must be different", false
+		}
+		seen[strings.ToLower(option)] = true
+		poll.Options[i] = option
+	}
+
+	if !pollOpenAfter(poll.ClosesAt, publishAt) {
+		return pollClosesAtMessage, false
+	}
+
+	return "", true
+}
+
+const pollClosesAtMessage = "Poll closes_at must be in the next 7 days after publish"
+
+/**
+ * Check closing time of poll for chirp published at publishAt
+ */
+func pollOpenAfter(closesAt, publishAt time.Time) bool {
+	return closesAt.After(publishAt) && closesAt.Sub(publishAt) <= maxPollDuration
+}
+
+/**
+ * Check poll of draft or scheduled chirp when it is published at publishAt,
+ * closes_at of a draft was checked only against time of creation
+ */
+func (confg *apiConfig) pollAllowsPublish(ctx context.Context, chirpID uuid.UUID, publishAt time.Time) (bool, error) {
+	polls, err := confg.db.GetPollsByChirpIDs(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return false, err
+	}
+	for _, poll := range polls {
+		if !pollOpenAfter(poll.ClosesAt, publishAt) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+/**
+ * Save poll of new chirp, must be called in the transaction which creates the chirp
+ */
+func createPoll(ctx context.Context, db *database.Queries, chirpID uuid.UUID, poll *PollRequest) error {
+	if poll == nil {
+		return nil
+	}
+
+	err := db.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: poll.ClosesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.CreatePollOptions(ctx, database.CreatePollOptionsParams{
+		ChirpID: chirpID,
+		Options: poll.Options,
+	})
+}
+
+/**
+ * Load polls of chirps with tallies and vote of the viewer
+ */
+func (confg *apiConfig) withPolls(ctx context.Context, viewerID uuid.UUID, chirps []*Chirpy) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		if !chirp.Deleted {
+			ids = append(ids, chirp.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	polls, err := confg.db.GetPollsByChirpIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(polls) == 0 {
+		return nil
+	}
+
+	byID := map[uuid.UUID]*Poll{}
+	pollIDs := make([]uuid.UUID, len(polls))
+	for i, poll := range polls {
+		byID[poll.ChirpID] = &Poll{
+			Options:  []PollOption{},
+			ClosesAt: poll.ClosesAt,
+			Closed:   !poll.ClosesAt.After(time.Now().UTC()),
+		}
+		pollIDs[i] = poll.ChirpID
+	}
+
+	tallies, err := confg.db.GetPollTallies(ctx, pollIDs)
+	if err != nil {
+		return err
+	}
+	for _, tally := range tallies {
+		poll := byID[tally.ChirpID]
+		poll.Options = append(poll.Options, PollOption{Text: tally.Text, Votes: tally.Votes})
+		poll.TotalVotes += tally.Votes
+	}
+
+	if viewerID != uuid.Nil {
+		votes, err := confg.db.GetViewerVotes(ctx, database.GetViewerVotesParams{
+			UserID:   viewerID,
+			ChirpIds: pollIDs,
+		})
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			position := vote.Position
+			byID[vote.ChirpID].Voted = true
+			byID[vote.ChirpID].ViewerVote = &position
+		}
+	}
+
+	for _, chirp := range chirps {
+		if poll, ok := byID[chirp.ID]; ok {
+			chirp.Poll = poll
+		}
+	}
+	return nil
+}
+
+/**
+ * Handle vote in poll of chirp, body is {"option": 0} with index of option. User can vote only once
+ */
+func (confg *apiConfig) handleCreatePollVote(w http.ResponseWriter, r *http.Request) {
+	type requestVote struct {
+		Option *int32 `json:"option"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var voteReq requestVote
+	err = json.NewDecoder(r.Body).Decode(&voteReq)
+	if err != nil || voteReq.Option == nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil || !isPublic(chirp) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	poll, err := confg.db.GetPollByChirpID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Poll doesn`t found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !poll.ClosesAt.After(time.Now().UTC()) {
+		respondWithError(w, http.StatusBadRequest, "Poll is closed")
+		return
+	}
+
+	count, err := confg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		ChirpID:  chirpID,
+		UserID:   userID,
+		Position: *voteReq.Option,
+	})
+	//Option doesn't exist
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusBadRequest, "Unknown poll option")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if count == 0 {
+		respondWithError(w, http.StatusConflict, "Already voted")
+		return
+	}
+
+	chirpsResponse := []Chirpy{toChirpy(chirp)}
+	err = confg.withPolls(r.Context(), userID, []*Chirpy{&chirpsResponse[0]})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirpsResponse[0].Poll)
+}
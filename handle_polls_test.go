@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestCreatePollVote(t *testing.T) {
+	voterID, authorID, chirpID := uuid.New(), uuid.New(), uuid.New()
+	target := "/api/chirps/" + chirpID.String() + "/poll/votes"
+	published := database.Chirp{ID: chirpID, UserID: authorID, Status: chirpPublished}
+	draft := database.Chirp{ID: chirpID, UserID: authorID, Status: chirpDraft}
+	deleted := published
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	open := database.Poll{ChirpID: chirpID, ClosesAt: time.Now().Add(time.Hour)}
+	closed := database.Poll{ChirpID: chirpID, ClosesAt: time.Now().Add(-time.Hour)}
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		chirp  *database.Chirp
+		hidden bool
+		poll   *database.Poll
+		vote   fakeResult
+		want   int
+	}{
+		{name: "missing option", target: target, body: `{}`, want: http.StatusBadRequest},
+		{name: "invalid chirpID", target: "/api/chirps/abc/poll/votes", body: `{"option": 0}`, want: http.StatusBadRequest},
+		{name: "unknown chirp", target: target, body: `{"option": 0}`, want: http.StatusNotFound},
+		// Author blocked the voter
+		{name: "hidden chirp", target: target, body: `{"option": 0}`, chirp: &published, hidden: true, poll: &open, want: http.StatusNotFound},
+		{name: "draft", target: target, body: `{"option": 0}`, chirp: &draft, poll: &open, want: http.StatusNotFound},
+		{name: "deleted", target: target, body: `{"option": 0}`, chirp: &deleted, poll: &open, want: http.StatusNotFound},
+		{name: "no poll", target: target, body: `{"option": 0}`, chirp: &published, want: http.StatusNotFound},
+		{name: "closed", target: target, body: `{"option": 0}`, chirp: &published, poll: &closed, want: http.StatusBadRequest},
+		{
+			name: "unknown option", target: target, body: `{"option": 7}`, chirp: &published, poll: &open,
+			vote: fakeResult{Err: &pq.Error{Code: "23503"}}, want: http.StatusBadRequest,
+		},
+		{name: "already voted", target: target, body: `{"option": 0}`, chirp: &published, poll: &open, want: http.StatusConflict},
+		{name: "voted", target: target, body: `{"option": 0}`, chirp: &published, poll: &open, vote: fakeResult{Affected: 1}, want: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			switch {
+			case tt.chirp == nil:
+				db.on("GetVisibleChirpByID", fakeResult{})
+			case tt.hidden:
+				onVisibleChirp(db, *tt.chirp, voterID)
+			default:
+				onVisibleChirp(db, *tt.chirp)
+			}
+			if tt.poll != nil {
+				db.on("GetPollByChirpID", rows(*tt.poll))
+				db.on("GetPollsByChirpIDs", rows(*tt.poll))
+			} else {
+				db.on("GetPollByChirpID", fakeResult{})
+			}
+			db.on("CreatePollVote", tt.vote)
+			db.on("GetPollTallies", rows(
+				database.GetPollTalliesRow{ChirpID: chirpID, Position: 0, Text: "Yes", Votes: 1},
+				database.GetPollTalliesRow{ChirpID: chirpID, Position: 1, Text: "No", Votes: 0},
+			))
+			db.on("GetViewerVotes", rows(database.GetViewerVotesRow{ChirpID: chirpID, Position: 0}))
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/chirps/{chirpID}/poll/votes", cfg.handleCreatePollVote, tt.target, testToken(t, voterID), tt.body)
+			if tt.want != http.StatusCreated {
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+				if tt.hidden && db.ran("CreatePollVote") != 0 {
+					t.Error("vote was saved")
+				}
+				return
+			}
+
+			var got Poll
+			decodeResponse(t, rec, http.StatusCreated, &got)
+			if !got.Voted || got.ViewerVote == nil || *got.ViewerVote != 0 || got.TotalVotes != 1 {
+				t.Errorf("poll = %+v, want vote of the viewer for the first option", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/textlen"
+	"github.com/google/uuid"
+)
+
+var errInvalidAvatar = errors.New("invalid avatar")
+
+// Max length of profile fields in user-perceived characters
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	FollowersCount int64     `json:"followers_count"`
+	FollowingCount int64     `json:"following_count"`
+	ChirpsCount    int64     `json:"chirps_count"`
+}
+
+/**
+ * Compact author embedded into chirps with ?include=author
+ */
+type Author struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"display_name,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+}
+
+func avatarURL(key sql.NullString) string {
+	if !key.Valid {
+		return ""
+	}
+	return mediaPath + key.String
+}
+
+/**
+ * Get URL of avatar of the user, empty when the user has no avatar
+ */
+func (cfg *apiConfig) userAvatarURL(ctx context.Context, avatarID uuid.NullUUID) string {
+	if !avatarID.Valid {
+		return ""
+	}
+	medium, err := cfg.db.GetMediaByID(ctx, avatarID.UUID)
+	if err != nil {
+		return ""
+	}
+	return mediaPath + medium.BlobKey
+}
+
+/**
+ * Handle public profile of the user by handle. Chirps are counted as the viewer can read them,
+ * users blocked by the owner of the profile get 404
+ */
+func (cfg *apiConfig) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	handle := strings.TrimPrefix(r.PathValue("handle"), "@")
+
+	row, err := cfg.db.GetUserProfile(r.Context(), database.GetUserProfileParams{
+		ViewerID: cfg.viewerID(r),
+		Handle:   handle,
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 200, Profile{
+		ID:             row.ID,
+		Handle:         row.Handle.String,
+		DisplayName:    row.DisplayName,
+		Bio:            row.Bio,
+		AvatarURL:      avatarURL(row.AvatarKey),
+		CreatedAt:      row.CreatedAt,
+		FollowersCount: row.FollowersCount,
+		FollowingCount: row.FollowingCount,
+		ChirpsCount:    row.ChirpsCount,
+	})
+}
+
+/**
+ * Handle update profile of the authenticated user. Display name, bio and avatar are replaced,
+ * empty values clear them. Empty handle keeps the current one
+ */
+func (cfg *apiConfig) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	var params struct {
+		Handle      string     `json:"handle"`
+		DisplayName string     `json:"display_name"`
+		Bio         string     `json:"bio"`
+		AvatarID    *uuid.UUID `json:"avatar_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	handle, ok := parseHandle(params.Handle)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid handle")
+		return
+	}
+
+	displayName := strings.TrimSpace(params.DisplayName)
+	if textlen.Length(displayName, textlen.DefaultURLWeight) > maxDisplayNameLength {
+		respondWithError(w, http.StatusBadRequest, "Display name is too long")
+		return
+	}
+	bio := strings.TrimSpace(params.Bio)
+	if textlen.Length(bio, textlen.DefaultURLWeight) > maxBioLength {
+		respondWithError(w, http.StatusBadRequest, "Bio is too long")
+		return
+	}
+
+	avatarID := uuid.NullUUID{}
+	if params.AvatarID != nil {
+		avatarID = uuid.NullUUID{UUID: *params.AvatarID, Valid: true}
+	}
+
+	var userDb database.User
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		// Avatar is an own upload which isn't attached to a chirp, marked one can't be attached later
+		if avatarID.Valid {
+			marked, err := q.MarkMediaAvatar(r.Context(), database.MarkMediaAvatarParams{ID: avatarID.UUID, UserID: userID})
+			if err != nil {
+				return err
+			}
+			if marked == 0 {
+				return errInvalidAvatar
+			}
+		}
+
+		var err error
+		userDb, err = q.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+			Handle:      handle,
+			DisplayName: displayName,
+			Bio:         bio,
+			AvatarID:    avatarID,
+			ID:          userID,
+		})
+		return err
+	})
+	if errors.Is(err, errInvalidAvatar) {
+		respondWithError(w, http.StatusBadRequest, "Invalid avatar")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Handle is already taken")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 200, cfg.toUser(r.Context(), userDb))
+}
+
+/**
+ * Check if optional part of response is requested, e.g. ?include=author
+ */
+func isIncluded(r *http.Request, part string) bool {
+	return containe(strings.Split(r.URL.Query().Get("include"), ","), part)
+}
+
+/**
+ * Embed authors of chirps, deleted chirps have no author
+ */
+func (confg *apiConfig) withAuthors(ctx context.Context, chirps []*Chirpy) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		if !chirp.Deleted {
+			ids = append(ids, chirp.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := confg.db.GetAuthorsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	authors := make(map[uuid.UUID]*Author, len(rows))
+	for _, row := range rows {
+		authors[row.ID] = &Author{
+			ID:          row.ID,
+			Handle:      row.Handle.String,
+			DisplayName: row.DisplayName,
+			AvatarURL:   avatarURL(row.AvatarKey),
+		}
+	}
+
+	for _, chirp := range chirps {
+		if !chirp.Deleted {
+			chirp.Author = authors[chirp.UserID]
+		}
+	}
+	return nil
+}
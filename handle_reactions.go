@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+const reactionLike = "like"
+
+// Reactions which are allowed when REACTION_TYPES is not set
+var defaultReactionTypes = []string{reactionLike, "❤️", "😂", "😮", "😢", "🔥"}
+
+/**
+ * Parse comma separated list of reaction types. Like is always allowed
+ */
+func parseReactionTypes(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return defaultReactionTypes
+	}
+
+	reactionTypes := []string{reactionLike}
+	for _, reaction := range strings.Split(list, ",") {
+		reaction = strings.TrimSpace(reaction)
+		if reaction == "" || containe(reactionTypes, reaction) {
+			continue
+		}
+		reactionTypes = append(reactionTypes, reaction)
+	}
+	return reactionTypes
+}
+
+/**
+ * Find configured reaction type, case of the requested one doesn't matter
+ */
+func (confg *apiConfig) reactionType(reaction string) (string, bool) {
+	for _, reactionType := range confg.reactionTypes {
+		if strings.EqualFold(reactionType, reaction) {
+			return reactionType, true
+		}
+	}
+	return "", false
+}
+
+/**
+ * Handle add reaction to chirp
+ */
+func (confg *apiConfig) handleCreateReaction(w http.ResponseWriter, r *http.Request) {
+	type requestReaction struct {
+		Type string `json:"type"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var reactionReq requestReaction
+	err = json.NewDecoder(r.Body).Decode(&reactionReq)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Stored and emitted as configured, so counts don't split by case
+	reaction, ok := confg.reactionType(reactionReq.Type)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Unknown reaction type")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := confg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil || !isPublic(chirp) {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	err = confg.db.CreateReaction(r.Context(), database.CreateReactionParams{
+		ChirpID:  chirp.ID,
+		UserID:   userID,
+		Reaction: reaction,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	confg.emit(r.Context(), events.Event{Type: events.ChirpReacted, ActorID: userID, ChirpID: chirp.ID, Reaction: reaction})
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+/**
+ * Handle remove reaction from chirp, type of reaction is in query parameter
+ */
+func (confg *apiConfig) handleDeleteReaction(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	reaction := r.URL.Query().Get("type")
+	if reaction == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing type")
+		return
+	}
+	if reactionType, ok := confg.reactionType(reaction); ok {
+		reaction = reactionType
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	err = confg.db.DeleteReaction(r.Context(), database.DeleteReactionParams{
+		ChirpID:  chirpID,
+		UserID:   userID,
+		Reaction: reaction,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+/**
+ * Handle list of chirps liked by user, latest like first
+ */
+func (confg *apiConfig) handleGetUserLikes(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid userID")
+		return
+	}
+
+	limit, err := paging.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	cursor := paging.Start(true)
+	if cursorParam := r.URL.Query().Get("cursor"); cursorParam != "" {
+		cursor, err = paging.Decode(cursorParam)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	likes, err := confg.db.GetReactedChirpsByUserID(r.Context(), database.GetReactedChirpsByUserIDParams{
+		ViewerID:        confg.viewerID(r),
+		UserID:          userID,
+		Reaction:        reactionLike,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if len(likes) > limit {
+		likes = likes[:limit]
+		last := likes[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.ReactedAt, ID: last.Chirp.ID})
+	}
+
+	chirpsResponse := make([]Chirpy, len(likes))
+	for i, like := range likes {
+		chirpsResponse[i] = toChirpy(like.Chirp)
+	}
+
+	err = confg.enrichChirps(r, confg.viewerID(r), chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
+
+/**
+ * Fill reaction counts of chirps and reactions of viewer. viewerID is uuid.Nil for anonymous
+ */
+func (confg *apiConfig) withReactions(ctx context.Context, viewerID uuid.UUID, chirps []*Chirpy) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	index := make(map[uuid.UUID][]*Chirpy, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+		index[chirp.ID] = append(index[chirp.ID], chirp)
+	}
+
+	counts, err := confg.db.GetReactionCounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, count := range counts {
+		for _, chirp := range index[count.ChirpID] {
+			chirp.Reactions[count.Reaction] = count.Count
+		}
+	}
+
+	if viewerID == uuid.Nil {
+		return nil
+	}
+
+	viewerReactions, err := confg.db.GetViewerReactions(ctx, database.GetViewerReactionsParams{
+		UserID:   viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	for _, reaction := range viewerReactions {
+		for _, chirp := range index[reaction.ChirpID] {
+			chirp.ViewerReacted = append(chirp.ViewerReacted, reaction.Reaction)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/entities"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/stream"
+	"github.com/google/uuid"
+)
+
+// Channels of the stream. Author, tag and user channels are prefixes followed by id or tag name
+const (
+	channelGlobal   = "global"
+	channelTimeline = "timeline"
+	channelAuthor   = "author:"
+	channelTag      = "tag:"
+	channelUser     = "user:"
+)
+
+// Events pushed to the stream
+const (
+	streamChirpCreated        = "chirp.created"
+	streamChirpDeleted        = "chirp.deleted"
+	streamNotificationCreated = "notification.created"
+)
+
+// Channels which one connection can request, timeline counts as one
+const maxStreamChannels = 20
+
+/**
+ * Subscribe stream to domain events
+ */
+func (cfg *apiConfig) subscribeStream(bus *events.Bus) {
+	bus.Subscribe(events.ChirpPublished, cfg.streamChirpPublished)
+	bus.Subscribe(events.ChirpDeleted, cfg.streamChirpDeleted)
+}
+
+/**
+ * Handle stream of new chirps, deletes and notifications as Server-Sent Events
+ */
+func (cfg *apiConfig) handleStream(w http.ResponseWriter, r *http.Request) {
+	sub, ok := cfg.subscribeRequest(w, r)
+	if !ok {
+		return
+	}
+	stream.ServeSSE(w, r, sub)
+}
+
+/**
+ * Handle the same stream as handleStream over WebSocket
+ */
+func (cfg *apiConfig) handleStreamWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub, ok := cfg.subscribeRequest(w, r)
+	if !ok {
+		return
+	}
+	stream.ServeWebSocket(w, r, sub)
+}
+
+/**
+ * Subscribe to channels from ?channels=global,timeline,author:<id>,tag:<name>, global when it is empty.
+ * Authenticated user always gets own notifications. Token is taken from Authorization header or from
+ * ?access_token=, because browsers can't set headers of EventSource and WebSocket. Follows, blocks
+ * and mutes are loaded once, changes apply when client reconnects
+ */
+func (cfg *apiConfig) subscribeRequest(w http.ResponseWriter, r *http.Request) (*stream.Subscription, bool) {
+	if cfg.hub == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Stream is not available")
+		return nil, false
+	}
+
+	userID, err := streamUser(r, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return nil, false
+	}
+
+	requested, err := parseStreamChannels(r.URL.Query().Get("channels"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return nil, false
+	}
+
+	following := map[uuid.UUID]bool{}
+	hidden := map[uuid.UUID]bool{}
+	channels := []string{}
+	if userID != uuid.Nil {
+		followees, err := cfg.db.GetFolloweeIDs(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 500, "Something went wrong")
+			return nil, false
+		}
+		for _, followee := range followees {
+			following[followee] = true
+		}
+
+		hiddenIDs, err := cfg.db.GetHiddenUserIDs(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 500, "Something went wrong")
+			return nil, false
+		}
+		for _, hiddenID := range hiddenIDs {
+			hidden[hiddenID] = true
+		}
+
+		channels = append(channels, channelUser+userID.String())
+	}
+
+	for _, channel := range requested {
+		if channel != channelTimeline {
+			channels = append(channels, channel)
+			continue
+		}
+		if userID == uuid.Nil {
+			respondWithError(w, 401, "Timeline requires authentication")
+			return nil, false
+		}
+		channels = append(channels, channelAuthor+userID.String())
+		for followee := range following {
+			channels = append(channels, channelAuthor+followee.String())
+		}
+	}
+
+	filter := func(msg stream.Message) bool {
+		if hidden[msg.AuthorID] {
+			return false
+		}
+		if msg.Visibility == visibilityFollowers && msg.AuthorID != userID && !following[msg.AuthorID] {
+			return false
+		}
+		return true
+	}
+
+	return cfg.hub.Subscribe(channels, filter), true
+}
+
+/**
+ * Get id of user from token, uuid.Nil when there is no token. Invalid token is an error
+ */
+func streamUser(r *http.Request, tokenSecret string) (uuid.UUID, error) {
+	token := r.URL.Query().Get("access_token")
+	if r.Header.Get("Authorization") != "" {
+		var err error
+		token, err = auth.GetBearerToken(r.Header)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if token == "" {
+		return uuid.Nil, nil
+	}
+	return auth.ValidateJWT(token, tokenSecret)
+}
+
+/**
+ * Parse comma separated list of channels. User channels can't be requested, they are added by token
+ */
+func parseStreamChannels(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return []string{channelGlobal}, nil
+	}
+
+	channels := []string{}
+	for _, channel := range strings.Split(list, ",") {
+		channel = strings.TrimSpace(channel)
+		switch {
+		case channel == channelGlobal || channel == channelTimeline:
+		case strings.HasPrefix(channel, channelAuthor):
+			authorID, err := uuid.Parse(strings.TrimPrefix(channel, channelAuthor))
+			if err != nil {
+				return nil, errors.New("Invalid channel " + channel)
+			}
+			channel = channelAuthor + authorID.String()
+		case strings.HasPrefix(channel, channelTag):
+			tag := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(channel, channelTag), "#"))
+			if tag == "" || len([]rune(tag)) > entities.MaxTagLength {
+				return nil, errors.New("Invalid channel " + channel)
+			}
+			channel = channelTag + tag
+		default:
+			return nil, errors.New("Unknown channel " + channel)
+		}
+		if !containe(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	if len(channels) > maxStreamChannels {
+		return nil, errors.New("Too many channels")
+	}
+	return channels, nil
+}
+
+/**
+ * Channels where chirp is published: public chirps go everywhere, unlisted and followers-only
+ * only to the author channel, mentioned-only to user channels of mentioned users
+ */
+func (cfg *apiConfig) chirpChannels(ctx context.Context, chirp database.Chirp) ([]string, error) {
+	switch chirp.Visibility {
+	case visibilityPublic:
+		channels := []string{channelGlobal, channelAuthor + chirp.UserID.String()}
+		for _, tag := range entities.Names(entities.Extract(chirp.Body), entities.TypeHashtag) {
+			channels = append(channels, channelTag+tag)
+		}
+		return channels, nil
+	case visibilityMentioned:
+		mentions, err := cfg.db.GetMentionsByChirpIDs(ctx, []uuid.UUID{chirp.ID})
+		if err != nil {
+			return nil, err
+		}
+		channels := []string{channelUser + chirp.UserID.String()}
+		for _, mention := range mentions {
+			channels = append(channels, channelUser+mention.UserID.String())
+		}
+		return channels, nil
+	default:
+		return []string{channelAuthor + chirp.UserID.String()}, nil
+	}
+}
+
+func (cfg *apiConfig) streamChirpPublished(ctx context.Context, event events.Event) error {
+	return cfg.publishStreamRef(ctx, streamChirpCreated, event.ChirpID)
+}
+
+func (cfg *apiConfig) streamChirpDeleted(ctx context.Context, event events.Event) error {
+	return cfg.publishStreamRef(ctx, streamChirpDeleted, event.ChirpID)
+}
+
+/**
+ * Publish chirp event only with id of the chirp, chirp of Chirpy Red doesn't fit NOTIFY payload.
+ * Every instance loads the chirp in loadStreamMessage
+ */
+func (cfg *apiConfig) publishStreamRef(ctx context.Context, event string, chirpID uuid.UUID) error {
+	if cfg.stream == nil {
+		return nil
+	}
+	return cfg.stream.Publish(ctx, stream.Message{Event: event, Ref: chirpID.String()})
+}
+
+/**
+ * Load chirp of message published by reference and build the message for subscribers
+ */
+func (cfg *apiConfig) loadStreamMessage(ctx context.Context, ref stream.Message) (stream.Message, bool, error) {
+	chirpID, err := uuid.Parse(ref.Ref)
+	if err != nil {
+		return stream.Message{}, false, err
+	}
+	chirp, err := cfg.db.GetChirpByID(ctx, chirpID)
+	// Purged before the message came
+	if errors.Is(err, sql.ErrNoRows) {
+		return stream.Message{}, false, nil
+	}
+	if err != nil {
+		return stream.Message{}, false, err
+	}
+	channels, err := cfg.chirpChannels(ctx, chirp)
+	if err != nil {
+		return stream.Message{}, false, err
+	}
+
+	var payload interface{}
+	switch ref.Event {
+	case streamChirpCreated:
+		chirpy := toChirpy(chirp)
+		chirps := []*Chirpy{&chirpy}
+		if err := cfg.withAttachments(ctx, chirps); err != nil {
+			return stream.Message{}, false, err
+		}
+		if err := cfg.withMentions(ctx, chirps); err != nil {
+			return stream.Message{}, false, err
+		}
+		payload = chirpy
+	case streamChirpDeleted:
+		payload = struct {
+			ID uuid.UUID `json:"id"`
+		}{ID: chirp.ID}
+	default:
+		return stream.Message{}, false, fmt.Errorf("unknown stream event %s", ref.Event)
+	}
+
+	msg, err := newStreamMessage(ref.Event, channels, chirp.UserID, chirp.Visibility, payload)
+	return msg, err == nil, err
+}
+
+/**
+ * Push created notifications to user channels of their recipients
+ */
+func (cfg *apiConfig) streamNotifications(ctx context.Context, notifications []database.Notification) error {
+	var errs []error
+	for _, notification := range notifications {
+		err := cfg.publishStream(ctx, streamNotificationCreated, []string{channelUser + notification.UserID.String()},
+			notification.ActorID, "", toNotification(notification))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (cfg *apiConfig) publishStream(ctx context.Context, event string, channels []string, authorID uuid.UUID, visibility string, payload interface{}) error {
+	if cfg.stream == nil {
+		return nil
+	}
+
+	msg, err := newStreamMessage(event, channels, authorID, visibility, payload)
+	if err != nil {
+		return err
+	}
+	return cfg.stream.Publish(ctx, msg)
+}
+
+func newStreamMessage(event string, channels []string, authorID uuid.UUID, visibility string, payload interface{}) (stream.Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return stream.Message{}, err
+	}
+
+	return stream.Message{
+		Event:      event,
+		Channels:   channels,
+		AuthorID:   authorID,
+		Visibility: visibility,
+		Data:       data,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/stream"
+	"github.com/google/uuid"
+)
+
+func TestStreamLongRedChirp(t *testing.T) {
+	// Every family emoji is one character of 25 bytes
+	body := strings.Repeat("👨‍👩‍👧‍👦", redChirpLimit)
+	author := database.User{ID: uuid.New(), IsChirpyRed: sql.NullBool{Bool: true, Valid: true}}
+	if err := limitsForUser(author).check(body); err != nil {
+		t.Fatalf("chirp of %d bytes: %v", len(body), err)
+	}
+	chirp := database.Chirp{ID: uuid.New(), Body: body, UserID: author.ID, Status: chirpPublished, Visibility: visibilityPublic}
+
+	db := newFakeDB()
+	var payloads []string
+	db.onArgs("SELECT pg_notify($1, $2)", func(args []driver.Value) fakeResult {
+		for _, arg := range args {
+			if payload, ok := arg.(string); ok && strings.HasPrefix(payload, "{") {
+				payloads = append(payloads, payload)
+			}
+		}
+		return fakeResult{}
+	})
+	db.on("GetChirpByID", rows(chirp))
+	onChirpDetails(db)
+	cfg := newTestConfig(t, db)
+	cfg.stream = &stream.PGBridge{DB: cfg.conn, Hub: stream.NewHub(), Load: cfg.loadStreamMessage}
+
+	if err := cfg.streamChirpPublished(context.Background(), events.Event{Type: events.ChirpPublished, ChirpID: chirp.ID}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(payloads) != 1 || len(payloads[0]) >= 8000 {
+		t.Fatalf("NOTIFY payloads = %d, want one below 8000 bytes", len(payloads))
+	}
+
+	// Every instance loads the chirp from the reference
+	var ref stream.Message
+	if err := json.Unmarshal([]byte(payloads[0]), &ref); err != nil {
+		t.Fatal(err)
+	}
+	msg, ok, err := cfg.loadStreamMessage(context.Background(), ref)
+	if err != nil || !ok {
+		t.Fatalf("load = %v, %v", ok, err)
+	}
+	var got Chirpy
+	if err := json.Unmarshal(msg.Data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Event != streamChirpCreated || got.Body != body || msg.AuthorID != author.ID {
+		t.Errorf("message %s of %s with body of %d bytes, want the whole chirp", msg.Event, msg.AuthorID, len(got.Body))
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/paging"
+	"github.com/google/uuid"
+)
+
+// Deleted chirps can be restored during this time, after it purger removes them
+const trashRetention = 30 * 24 * time.Hour
+
+/**
+ * Deleted chirp with its body for the owner in trash or for admins
+ */
+func toDeletedChirpy(chirp database.Chirp) Chirpy {
+	chirpy := toChirpy(chirp)
+	chirpy.Body = chirp.Body
+	chirpy.Entities = toEntities(chirp.Body)
+	if chirp.DeletedAt.Valid {
+		chirpy.DeletedAt = &chirp.DeletedAt.Time
+	}
+	return chirpy
+}
+
+/**
+ * Respond with page of deleted chirps ordered by (deleted_at, id)
+ */
+func respondWithDeletedPage(w http.ResponseWriter, r *http.Request, chirps []database.Chirp, limit int) {
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		last := chirps[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.DeletedAt.Time, ID: last.ID})
+	}
+
+	chirpsResponse := make([]Chirpy, len(chirps))
+	for i, chirp := range chirps {
+		chirpsResponse[i] = toDeletedChirpy(chirp)
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
+
+/**
+ * Handle list of deleted chirps of the authenticated user which still can be restored, latest deleted first
+ */
+func (confg *apiConfig) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := confg.db.GetDeletedChirpsByUserID(r.Context(), database.GetDeletedChirpsByUserIDParams{
+		UserID:          userID,
+		DeletedSince:    time.Now().UTC().Add(-trashRetention),
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithDeletedPage(w, r, chirps, limit)
+}
+
+/**
+ * Handle restore of deleted chirp by the owner, it is possible during 30 days after delete
+ */
+func (confg *apiConfig) handleRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, confg.tokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := confg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil || !chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "Forbidden")
+		return
+	}
+
+	chirp, err = confg.db.RestoreChirpByID(r.Context(), database.RestoreChirpByIDParams{
+		ID:           chirpID,
+		DeletedSince: time.Now().UTC().Add(-trashRetention),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusGone, "Chirp can`t be restored after 30 days")
+		return
+	}
+	// The same chirp was rechirped again after delete
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Chirp is already rechirped")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// Restored chirp is visible again, like a published draft
+	if isPublic(chirp) {
+		confg.emit(r.Context(), events.Event{Type: events.ChirpPublished, ActorID: userID, ChirpID: chirp.ID})
+	}
+
+	chirpsResponse := []Chirpy{toChirpy(chirp)}
+	err = confg.enrichChirps(r, userID, chirpsResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse[0])
+}
+
+/**
+ * Handle list of all deleted chirps with bodies for admins, optional author_id
+ */
+func (cfg *apiConfig) handleAdminGetDeletedChirps(w http.ResponseWriter, r *http.Request) {
+	authorID := uuid.NullUUID{}
+	if author := r.URL.Query().Get("author_id"); author != "" {
+		id, err := uuid.Parse(author)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author_id")
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	limit, cursor, err := parsePage(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := cfg.db.GetDeletedChirps(r.Context(), database.GetDeletedChirpsParams{
+		AuthorID:        authorID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithDeletedPage(w, r, chirps, limit)
+}
+
+/**
+ * Handle get any chirp for admins, deleted chirp is shown with body until it is purged
+ */
+func (cfg *apiConfig) handleAdminGetChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirpID")
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirpy doesn`t found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toDeletedChirpy(chirp))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestRestoreChirp(t *testing.T) {
+	ownerID, chirpID := uuid.New(), uuid.New()
+	target := "/api/chirps/" + chirpID.String() + "/restore"
+	live := database.Chirp{ID: chirpID, Body: "hello", UserID: ownerID, Status: chirpPublished}
+	deleted := live
+	deleted.DeletedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+
+	tests := []struct {
+		name     string
+		userID   uuid.UUID
+		target   string
+		chirp    *database.Chirp
+		restored *database.Chirp
+		conflict bool
+		want     int
+	}{
+		{name: "invalid chirpID", userID: ownerID, target: "/api/chirps/abc/restore", want: http.StatusBadRequest},
+		{name: "unknown chirp", userID: ownerID, target: target, want: http.StatusNotFound},
+		{name: "chirp not in trash", userID: ownerID, target: target, chirp: &live, want: http.StatusNotFound},
+		{name: "chirp of other user", userID: uuid.New(), target: target, chirp: &deleted, restored: &live, want: http.StatusForbidden},
+		// Restore query doesn't find chirps deleted before the retention
+		{name: "deleted long ago", userID: ownerID, target: target, chirp: &deleted, want: http.StatusGone},
+		// Rechirp of the same chirp was made again after delete
+		{name: "rechirped again", userID: ownerID, target: target, chirp: &deleted, conflict: true, want: http.StatusConflict},
+		{name: "owner", userID: ownerID, target: target, chirp: &deleted, restored: &live, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.chirp != nil {
+				db.on("GetChirpByID", rows(*tt.chirp))
+			} else {
+				db.on("GetChirpByID", fakeResult{})
+			}
+			switch {
+			case tt.conflict:
+				db.on("RestoreChirpByID", fakeResult{Err: &pq.Error{Code: "23505"}})
+			case tt.restored != nil:
+				db.on("RestoreChirpByID", rows(*tt.restored))
+			default:
+				db.on("RestoreChirpByID", fakeResult{})
+			}
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+
+			rec := serveTest("POST /api/chirps/{chirpID}/restore", cfg.handleRestoreChirp, tt.target, testToken(t, tt.userID), "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusForbidden && db.ran("RestoreChirpByID") != 0 {
+				t.Error("chirp of other user was restored")
+			}
+		})
+	}
+}
+
+func TestGetTrashShowsOwnChirps(t *testing.T) {
+	ownerID := uuid.New()
+	deleted := database.Chirp{
+		ID: uuid.New(), Body: "hello", UserID: ownerID, Status: chirpPublished,
+		DeletedAt: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true},
+	}
+
+	db := newFakeDB()
+	db.onArgs("GetDeletedChirpsByUserID", func(args []driver.Value) fakeResult {
+		if hasArg(args, ownerID) {
+			return rows(deleted)
+		}
+		return fakeResult{}
+	})
+	cfg := newTestConfig(t, db)
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		want   int
+	}{
+		{name: "owner", userID: ownerID, want: 1},
+		{name: "other user", userID: uuid.New(), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTest("GET /api/trash", cfg.handleGetTrash, "/api/trash", testToken(t, tt.userID), "")
+			var got []Chirpy
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if len(got) != tt.want {
+				t.Fatalf("got %d chirps, want %d", len(got), tt.want)
+			}
+			if tt.want > 0 && (got[0].Body != "hello" || got[0].DeletedAt == nil) {
+				t.Errorf("chirp in trash = %+v, want body and deleted_at", got[0])
+			}
+		})
+	}
+}
+
+func TestRestoreChirpPublishesEvent(t *testing.T) {
+	ownerID, chirpID := uuid.New(), uuid.New()
+	published := database.Chirp{ID: chirpID, Body: "hello", UserID: ownerID, Status: chirpPublished}
+	draft := published
+	draft.Status = chirpDraft
+
+	tests := []struct {
+		name     string
+		restored database.Chirp
+		want     int
+	}{
+		{name: "published chirp", restored: published, want: 1},
+		// Draft is still seen only by the owner
+		{name: "draft", restored: draft, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := tt.restored
+			deleted.DeletedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+
+			db := newFakeDB()
+			db.on("GetChirpByID", rows(deleted))
+			db.on("RestoreChirpByID", rows(tt.restored))
+			onChirpDetails(db)
+			cfg := newTestConfig(t, db)
+			var published []events.Event
+			cfg.events = events.NewBus()
+			cfg.events.Subscribe(events.ChirpPublished, func(ctx context.Context, event events.Event) error {
+				published = append(published, event)
+				return nil
+			})
+
+			rec := serveTest("POST /api/chirps/{chirpID}/restore", cfg.handleRestoreChirp, "/api/chirps/"+chirpID.String()+"/restore", testToken(t, ownerID), "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if len(published) != tt.want {
+				t.Fatalf("published %d events, want %d", len(published), tt.want)
+			}
+			if tt.want == 1 && published[0].ChirpID != chirpID {
+				t.Errorf("event of chirp %s, want %s", published[0].ChirpID, chirpID)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// Time to enter the second factor after the password
+	mfaTokenTTL = 5 * time.Minute
+	// Codes which can be tried with one login by password
+	mfaChallengeAttempts = 5
+	// Failed codes of the user in mfaFailureWindow, after that login with 2FA is refused for a while
+	mfaUserFailures  = 10
+	mfaFailureWindow = 15 * time.Minute
+	// Recovery codes given when 2FA is enabled
+	recoveryCodeCount = 10
+	// Issuer shown in authenticator apps
+	totpIssuer = "Chirpy"
+)
+
+var errInvalidCode = errors.New("invalid code")
+
+/**
+ * Response of login with correct password when the user has 2FA
+ */
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+/**
+ * Start login which waits for the second factor, the challenge is kept to limit its use
+ */
+func (cfg *apiConfig) createMFAChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	challengeID := uuid.New()
+	err := cfg.db.CreateMFAChallenge(ctx, database.CreateMFAChallengeParams{
+		ID:        challengeID,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(mfaTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return auth.MakeMFAToken(userID, challengeID, cfg.tokenSecret, mfaTokenTTL)
+}
+
+/**
+ * Check if the user failed the second factor too many times recently
+ */
+func (cfg *apiConfig) tooManyMFAFailures(ctx context.Context, userID uuid.UUID) (bool, error) {
+	failures, err := cfg.db.CountMFAFailures(ctx, database.CountMFAFailuresParams{
+		UserID: userID,
+		Since:  time.Now().UTC().Add(-mfaFailureWindow),
+	})
+	return failures >= mfaUserFailures, err
+}
+
+/**
+ * Check TOTP code or recovery code of the user. Both work once: TOTP code of used time step
+ * and used recovery code are refused
+ */
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)),
+		})
+		return used == 1, err
+	}
+
+	totp, err := cfg.db.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !totp.EnabledAt.Valid {
+		return false, nil
+	}
+
+	counter, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	used, err := cfg.db.UseTOTPCounter(ctx, database.UseTOTPCounterParams{Counter: counter, UserID: userID})
+	return used == 1, err
+}
+
+/**
+ * Handle start of 2FA enrollment: new secret and otpauth:// URI for authenticator app.
+ * 2FA is enabled after the first code is verified
+ */
+func (cfg *apiConfig) handleSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	// Secret of enabled 2FA is kept, setup can be repeated only before verification
+	created, err := cfg.db.CreateTOTPSecret(r.Context(), database.CreateTOTPSecretParams{UserID: userID, Secret: secret})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if created == 0 {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	respondWithJSON(w, 200, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+/**
+ * Handle confirm of 2FA enrollment with the first code, body is {"code": "123456"}.
+ * Recovery codes are returned only here, they are stored hashed. Refresh tokens of the user are revoked,
+ * so sessions started without the second factor end
+ */
+func (cfg *apiConfig) handleVerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	var params struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Two-factor setup is not started")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if totp.EnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	counter, ok := auth.ValidateTOTP(totp.Secret, params.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(code)
+	}
+
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		enabled, err := q.EnableTOTP(r.Context(), database.EnableTOTPParams{Counter: counter, UserID: userID})
+		if err != nil {
+			return err
+		}
+		// Concurrent verify or new setup changed the secret meanwhile
+		if enabled == 0 {
+			return errInvalidCode
+		}
+		err = q.RevokeUserRefreshTokens(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		err = q.DeleteRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		return q.CreateRecoveryCodes(r.Context(), database.CreateRecoveryCodesParams{CodeHashes: hashes, UserID: userID})
+	})
+	if errors.Is(err, errInvalidCode) {
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 200, map[string][]string{"recovery_codes": codes})
+}
+
+/**
+ * Handle disable 2FA, body is {"code": "123456"} or {"recovery_code": "..."}
+ */
+func (cfg *apiConfig) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	var params struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), userID, params.Code, params.RecoveryCode)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.DeleteRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		return q.DeleteTOTP(r.Context(), userID)
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle second step of login with 2FA, body is {"mfa_token": "...", "code": "123456"}
+ * or {"mfa_token": "...", "recovery_code": "..."}. Tokens are issued like in login.
+ * MFA token works once and allows a few attempts
+ */
+func (cfg *apiConfig) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID, challengeID, err := auth.ValidateMFAToken(params.MFAToken, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	tooMany, err := cfg.tooManyMFAFailures(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if tooMany {
+		respondWithError(w, http.StatusTooManyRequests, "Too many failed attempts, try again later")
+		return
+	}
+
+	// Attempt is counted before the check, so parallel requests can't exceed the limit
+	claimed, err := cfg.db.ClaimMFAChallenge(r.Context(), database.ClaimMFAChallengeParams{
+		ID:          challengeID,
+		UserID:      userID,
+		MaxAttempts: mfaChallengeAttempts,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if claimed == 0 {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), userID, params.Code, params.RecoveryCode)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if !ok {
+		respondWithError(w, 401, "Invalid code")
+		return
+	}
+
+	completed, err := cfg.db.CompleteMFAChallenge(r.Context(), challengeID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if completed == 0 {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	userDb, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	cfg.issueTokens(w, r, userDb, true)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/google/uuid"
+)
+
+/**
+ * Answer query of single-use value like the database: the same arguments update a row once
+ */
+func onUseOnce(db *fakeDB, name string) {
+	var mu sync.Mutex
+	used := map[string]bool{}
+	db.onArgs(name, func(args []driver.Value) fakeResult {
+		mu.Lock()
+		defer mu.Unlock()
+		key := fmt.Sprint(args)
+		if used[key] {
+			return fakeResult{}
+		}
+		used[key] = true
+		return fakeResult{Affected: 1}
+	})
+}
+
+/**
+ * Answer queries of MFA challenges like the database: challenge allows a few attempts and completes once
+ */
+func onMFAChallenges(db *fakeDB, challengeIDs ...uuid.UUID) {
+	var mu sync.Mutex
+	attempts := map[uuid.UUID]int{}
+	used := map[uuid.UUID]bool{}
+	challenge := func(args []driver.Value) (uuid.UUID, bool) {
+		for _, id := range challengeIDs {
+			if hasArg(args, id) {
+				return id, true
+			}
+		}
+		return uuid.Nil, false
+	}
+
+	db.on("CountMFAFailures", fakeResult{Rows: [][]driver.Value{{int64(0)}}})
+	db.onArgs("ClaimMFAChallenge", func(args []driver.Value) fakeResult {
+		mu.Lock()
+		defer mu.Unlock()
+		id, ok := challenge(args)
+		if !ok || used[id] || attempts[id] >= mfaChallengeAttempts {
+			return fakeResult{}
+		}
+		attempts[id]++
+		return fakeResult{Affected: 1}
+	})
+	db.onArgs("CompleteMFAChallenge", func(args []driver.Value) fakeResult {
+		mu.Lock()
+		defer mu.Unlock()
+		id, ok := challenge(args)
+		if !ok || used[id] {
+			return fakeResult{}
+		}
+		used[id] = true
+		return fakeResult{Affected: 1}
+	})
+}
+
+/**
+ * Fake database of the user with enabled TOTP who can finish login
+ */
+func newTwoFactorDB(userID uuid.UUID, secret string, challengeIDs ...uuid.UUID) *fakeDB {
+	db := newFakeDB()
+	onMFAChallenges(db, challengeIDs...)
+	db.on("GetUserTOTP", rows(database.UserTotp{UserID: userID, Secret: secret, EnabledAt: sql.NullTime{Time: time.Now(), Valid: true}}))
+	onUseOnce(db, "UseTOTPCounter")
+	onUseOnce(db, "UseRecoveryCode")
+	db.on("GetUserByID", rows(database.User{ID: userID, Email: "user@example.com"}))
+	db.on("CreateToken", rows(database.RefreshToken{Token: "refresh", UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}))
+	return db
+}
+
+func mfaLoginBody(t *testing.T, userID, challengeID uuid.UUID, field, code string) string {
+	t.Helper()
+	mfaToken, err := auth.MakeMFAToken(userID, challengeID, testSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return `{"mfa_token": "` + mfaToken + `", "` + field + `": "` + code + `"}`
+}
+
+func TestLoginTwoFactorCodeWorksOnce(t *testing.T) {
+	userID, firstLogin, secondLogin := uuid.New(), uuid.New(), uuid.New()
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		code  string
+	}{
+		{name: "totp code", field: "code", code: code},
+		{name: "recovery code", field: "recovery_code", code: "abcd-efgh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, newTwoFactorDB(userID, secret, firstLogin, secondLogin))
+
+			rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", mfaLoginBody(t, userID, firstLogin, tt.field, tt.code))
+			var got UserToken
+			decodeResponse(t, rec, http.StatusOK, &got)
+			if got.Token == "" || !got.TwoFactorEnabled {
+				t.Errorf("response = %+v, want tokens after the second factor", got)
+			}
+
+			// The same code is refused in the next login after the password
+			rec = serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", mfaLoginBody(t, userID, secondLogin, tt.field, tt.code))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("replay status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+			}
+		})
+	}
+}
+
+func TestLoginTwoFactorChallenge(t *testing.T) {
+	userID, challengeID := uuid.New(), uuid.New()
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("works once", func(t *testing.T) {
+		db := newTwoFactorDB(userID, secret, challengeID)
+		cfg := newTestConfig(t, db)
+
+		for i, want := range []int{http.StatusOK, http.StatusUnauthorized} {
+			body := mfaLoginBody(t, userID, challengeID, "recovery_code", fmt.Sprintf("code-%d", i))
+			rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", body)
+			if rec.Code != want {
+				t.Fatalf("login %d: status = %d, want %d: %s", i+1, rec.Code, want, rec.Body)
+			}
+		}
+		if got := db.ran("CreateToken"); got != 1 {
+			t.Errorf("tokens issued %d times, want 1", got)
+		}
+	})
+
+	t.Run("allows a few attempts", func(t *testing.T) {
+		db := newTwoFactorDB(userID, secret, challengeID)
+		cfg := newTestConfig(t, db)
+
+		for i := 0; i < mfaChallengeAttempts; i++ {
+			rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", mfaLoginBody(t, userID, challengeID, "code", "12345"))
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("attempt %d: status = %d, want %d: %s", i+1, rec.Code, http.StatusUnauthorized, rec.Body)
+			}
+		}
+
+		// Valid code isn't even checked after the last attempt
+		code, err := auth.TOTPCode(secret, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", mfaLoginBody(t, userID, challengeID, "code", code))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+		}
+		if db.ran("UseTOTPCounter") != 0 {
+			t.Error("code was checked after the last attempt")
+		}
+	})
+
+	t.Run("completed by parallel login", func(t *testing.T) {
+		db := newTwoFactorDB(userID, secret, challengeID)
+		db.on("CompleteMFAChallenge", fakeResult{})
+		cfg := newTestConfig(t, db)
+
+		rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", mfaLoginBody(t, userID, challengeID, "recovery_code", "abcd-efgh"))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+		}
+		if db.ran("CreateToken") != 0 {
+			t.Error("tokens were issued twice for one challenge")
+		}
+	})
+
+	t.Run("too many failures of the user", func(t *testing.T) {
+		db := newTwoFactorDB(userID, secret, challengeID)
+		db.on("CountMFAFailures", fakeResult{Rows: [][]driver.Value{{int64(mfaUserFailures)}}})
+		cfg := newTestConfig(t, db)
+
+		rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "", mfaLoginBody(t, userID, challengeID, "recovery_code", "abcd-efgh"))
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusTooManyRequests, rec.Body)
+		}
+	})
+}
+
+func TestLoginTwoFactorNeedsMFAToken(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "missing", token: ""},
+		{name: "access token", token: testToken(t, userID)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, newFakeDB())
+
+			rec := serveTest("POST /api/login/2fa", cfg.handleLoginTwoFactor, "/api/login/2fa", "",
+				`{"mfa_token": "`+tt.token+`", "code": "123456"}`)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+			}
+		})
+	}
+}
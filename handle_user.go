@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/St5/goboot-srv/internal/auth"
 	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/entities"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/mail"
+	"github.com/St5/goboot-srv/internal/paging"
 	"github.com/google/uuid"
 )
 
@@ -15,7 +23,12 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Email     string    `json:"email"`
+	EmailVerified bool  `json:"email_verified"`
 	IsChirpyRed bool `json:"is_chirpy_red"`
+	Handle    string    `json:"handle,omitempty"`
+	DisplayName string  `json:"display_name"`
+	Bio       string    `json:"bio"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
 }
 
 type UserToken struct {
@@ -23,11 +36,25 @@ type UserToken struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
 	IsChirpyRed bool `json:"is_chirpy_red"`
+	Handle       string    `json:"handle,omitempty"`
+	DisplayName  string    `json:"display_name"`
+	Bio          string    `json:"bio"`
+	AvatarURL    string    `json:"avatar_url,omitempty"`
+	TwoFactorEnabled bool  `json:"two_factor_enabled"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 }
 
+// Unique index of emails, its violation means the email is taken
+const emailConstraint = "users_email_idx"
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
 /**
  * Handle create user
  */
@@ -35,6 +62,7 @@ func (cfg *apiConfig) handleUser(w http.ResponseWriter, r *http.Request) {
 	type request struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
 	//Decode request
@@ -47,6 +75,12 @@ func (cfg *apiConfig) handleUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.Email = strings.TrimSpace(req.Email)
+	if !mail.ValidAddress(req.Email) {
+		respondWithError(w, 400, "Invalid email")
+		return
+	}
+
 	//Validate password
 	pswrd, err := auth.HashPassword(req.Password)
 	if err != nil {
@@ -54,31 +88,44 @@ func (cfg *apiConfig) handleUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	handle, ok := parseHandle(req.Handle)
+	if !ok {
+		respondWithError(w, 400, "Invalid handle")
+		return
+	}
+
 	//Create user
 	userParams := database.CreateUserParams{
 		Email:          req.Email,
 		HashedPassword: pswrd,
+		Handle:         handle,
 	}
 	userDb, err := cfg.db.CreateUser(r.Context(), userParams)
+	if violatedConstraint(err) == emailConstraint {
+		respondWithError(w, 409, "Email is already taken")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Handle is already taken")
+		return
+	}
 	if err != nil {
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
-	user := User{
-		ID:        userDb.ID,
-		CreatedAt: userDb.CreatedAt,
-		UpdatedAt: userDb.UpdatedAt,
-		Email:     userDb.Email,
-		IsChirpyRed: userDb.IsChirpyRed.Bool,
+
+	// The user is created, verification email can be requested again
+	err = cfg.sendVerificationEmail(r.Context(), userDb)
+	if err != nil {
+		log.Printf("verification email for %s: %v", userDb.ID, err)
 	}
-	respondWithJSON(w, 201, user)
+	respondWithJSON(w, 201, cfg.toUser(r.Context(), userDb))
 }
 
 func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	type request struct {
 		Email            string `json:"email"`
 		Password         string `json:"password"`
-		expiresInSeconds int    `json:"expires_in_seconds"`
 	}
 
 	//Decode request
@@ -105,12 +152,41 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// An hour by default
-	expiresInSeconds := 3600
-
-	if req.expiresInSeconds > 0 && req.expiresInSeconds < 3600 {
-		expiresInSeconds = req.expiresInSeconds
+	// With 2FA the password only starts the login, tokens are issued for the second factor
+	enabled, err := cfg.db.IsTOTPEnabled(r.Context(), userDb.ID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
 	}
+	if enabled {
+		tooMany, err := cfg.tooManyMFAFailures(r.Context(), userDb.ID)
+		if err != nil {
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		if tooMany {
+			respondWithError(w, http.StatusTooManyRequests, "Too many failed attempts, try again later")
+			return
+		}
+
+		mfaToken, err := cfg.createMFAChallenge(r.Context(), userDb.ID)
+		if err != nil {
+			respondWithError(w, 500, "Token error")
+			return
+		}
+		respondWithJSON(w, 200, MFAChallenge{MFARequired: true, MFAToken: mfaToken})
+		return
+	}
+
+	cfg.issueTokens(w, r, userDb, false)
+}
+
+/**
+ * Respond with access and refresh tokens of the user who passed login
+ */
+func (cfg *apiConfig) issueTokens(w http.ResponseWriter, r *http.Request, userDb database.User, twoFactor bool) {
+	// An hour
+	expiresInSeconds := 3600
 
 	//Create token
 	token, err := auth.MakeJWT(userDb.ID, cfg.tokenSecret, time.Duration(expiresInSeconds)*time.Second)
@@ -145,7 +221,13 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:    userDb.CreatedAt,
 		UpdatedAt:    userDb.UpdatedAt,
 		Email:        userDb.Email,
+		EmailVerified: userDb.EmailVerifiedAt.Valid,
 		IsChirpyRed: userDb.IsChirpyRed.Bool,
+		Handle:       userDb.Handle.String,
+		DisplayName:  userDb.DisplayName,
+		Bio:          userDb.Bio,
+		AvatarURL:    cfg.userAvatarURL(r.Context(), userDb.AvatarID),
+		TwoFactorEnabled: twoFactor,
 		Token:        token,
 		RefreshToken: record.Token,
 	}
@@ -210,6 +292,7 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	type request struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
 	//Decode request
@@ -234,6 +317,12 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.Email = strings.TrimSpace(req.Email)
+	if !mail.ValidAddress(req.Email) {
+		respondWithError(w, 400, "Invalid email")
+		return
+	}
+
 	//Validate password
 	pswrd, err := auth.HashPassword(req.Password)
 	if err != nil {
@@ -241,25 +330,253 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	handle, ok := parseHandle(req.Handle)
+	if !ok {
+		respondWithError(w, 400, "Invalid handle")
+		return
+	}
+
+	current, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
 	userParam := database.UpdateUserParams{
 		ID:             userID,
 		Email:          req.Email,
 		HashedPassword: pswrd,
+		Handle:         handle,
 	}
 
 	userDb, err := cfg.db.UpdateUser(r.Context(), userParam)
+	if violatedConstraint(err) == emailConstraint {
+		respondWithError(w, 409, "Email is already taken")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Handle is already taken")
+		return
+	}
 	if err != nil {
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
-	user := User{
-		ID:        userDb.ID,
-		CreatedAt: userDb.CreatedAt,
-		UpdatedAt: userDb.UpdatedAt,
-		Email:     userDb.Email,
-		IsChirpyRed: userDb.IsChirpyRed.Bool,
+
+	// New email is not verified until the user confirms it
+	if !strings.EqualFold(current.Email, userDb.Email) {
+		err = cfg.sendVerificationEmail(r.Context(), userDb)
+		if err != nil {
+			log.Printf("verification email for %s: %v", userDb.ID, err)
+		}
 	}
-	respondWithJSON(w, 200, user)
+	respondWithJSON(w, 200, cfg.toUser(r.Context(), userDb))
 	
 }
 
+func (cfg *apiConfig) toUser(ctx context.Context, userDb database.User) User {
+	return User{
+		ID:          userDb.ID,
+		CreatedAt:   userDb.CreatedAt,
+		UpdatedAt:   userDb.UpdatedAt,
+		Email:         userDb.Email,
+		EmailVerified: userDb.EmailVerifiedAt.Valid,
+		IsChirpyRed:   userDb.IsChirpyRed.Bool,
+		Handle:      userDb.Handle.String,
+		DisplayName: userDb.DisplayName,
+		Bio:         userDb.Bio,
+		AvatarURL:   cfg.userAvatarURL(ctx, userDb.AvatarID),
+	}
+}
+
+/**
+ * Convert handle from request to database value. Empty handle is not set
+ */
+func parseHandle(handle string) (sql.NullString, bool) {
+	handle = strings.TrimPrefix(handle, "@")
+	if handle == "" {
+		return sql.NullString{}, true
+	}
+	if !entities.IsHandle(handle) {
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: handle, Valid: true}, true
+}
+
+/**
+ * Handle follow user by id
+ */
+func (cfg *apiConfig) handleFollowUser(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid userID")
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, 400, "You can`t follow yourself")
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		AuthorID: followeeID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "You are blocked by this user")
+		return
+	}
+
+	err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	cfg.emit(r.Context(), events.Event{Type: events.UserFollowed, ActorID: userID, UserID: followeeID})
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle unfollow user by id
+ */
+func (cfg *apiConfig) handleUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid userID")
+		return
+	}
+
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+/**
+ * Handle list of users who follow the user, newest first
+ */
+func (cfg *apiConfig) handleGetFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.respondWithFollows(w, r, cfg.db.GetFollowers, func(follow database.Follow) uuid.UUID {
+		return follow.FollowerID
+	})
+}
+
+/**
+ * Handle list of users followed by the user, newest first
+ */
+func (cfg *apiConfig) handleGetFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.respondWithFollows(w, r, func(ctx context.Context, arg database.GetFollowersParams) ([]database.Follow, error) {
+		return cfg.db.GetFollowing(ctx, database.GetFollowingParams(arg))
+	}, func(follow database.Follow) uuid.UUID {
+		return follow.FolloweeID
+	})
+}
+
+/**
+ * Paginate follows of user from path and respond with the other side of every follow
+ */
+func (cfg *apiConfig) respondWithFollows(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(context.Context, database.GetFollowersParams) ([]database.Follow, error),
+	otherSide func(database.Follow) uuid.UUID,
+) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid userID")
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	limit, err := paging.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid limit")
+		return
+	}
+
+	cursor := paging.Start(true)
+	if cursorParam := r.URL.Query().Get("cursor"); cursorParam != "" {
+		cursor, err = paging.Decode(cursorParam)
+		if err != nil {
+			respondWithError(w, 400, "Invalid cursor")
+			return
+		}
+	}
+
+	follows, err := list(r.Context(), database.GetFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	if len(follows) > limit {
+		follows = follows[:limit]
+		last := follows[limit-1]
+		setNextPageLink(w, r, paging.Cursor{CreatedAt: last.CreatedAt, ID: otherSide(last)})
+	}
+
+	followsResponse := make([]Follow, len(follows))
+	for i, follow := range follows {
+		followsResponse[i] = Follow{
+			UserID:     otherSide(follow),
+			FollowedAt: follow.CreatedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, followsResponse)
+}
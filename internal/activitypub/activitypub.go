@@ -0,0 +1,139 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	ContentType    = "application/activity+json"
+	JRDContentType = "application/jrd+json"
+
+	ActivityStreams = "https://www.w3.org/ns/activitystreams"
+	Security        = "https://w3id.org/security/v1"
+	// Audience of public objects
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Types of activities and objects which are sent or handled
+const (
+	TypeAccept    = "Accept"
+	TypeCreate    = "Create"
+	TypeDelete    = "Delete"
+	TypeFollow    = "Follow"
+	TypeLike      = "Like"
+	TypeUndo      = "Undo"
+	TypeNote      = "Note"
+	TypePerson    = "Person"
+	TypeOrdered   = "OrderedCollection"
+	TypeTombstone = "Tombstone"
+)
+
+type Actor struct {
+	Context           interface{} `json:"@context,omitempty"`
+	ID                string      `json:"id"`
+	Type              string      `json:"type"`
+	PreferredUsername string      `json:"preferredUsername"`
+	Name              string      `json:"name,omitempty"`
+	Summary           string      `json:"summary,omitempty"`
+	Inbox             string      `json:"inbox"`
+	Outbox            string      `json:"outbox,omitempty"`
+	Followers         string      `json:"followers,omitempty"`
+	URL               string      `json:"url,omitempty"`
+	PublicKey         PublicKey   `json:"publicKey"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+/**
+ * Activity with object as it came: object can be a link or an embedded object
+ */
+type Activity struct {
+	Context   interface{}     `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+	Published string          `json:"published,omitempty"`
+}
+
+type Note struct {
+	Context      interface{} `json:"@context,omitempty"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo string      `json:"attributedTo"`
+	Content      string      `json:"content"`
+	URL          string      `json:"url,omitempty"`
+	InReplyTo    string      `json:"inReplyTo,omitempty"`
+	Published    string      `json:"published"`
+	Updated      string      `json:"updated,omitempty"`
+	To           []string    `json:"to,omitempty"`
+	Cc           []string    `json:"cc,omitempty"`
+}
+
+type OrderedCollection struct {
+	Context      interface{}   `json:"@context,omitempty"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int64         `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems,omitempty"`
+}
+
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+/**
+ * Object of activity from properties which are needed to handle it
+ */
+type Object struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Actor        string `json:"actor"`
+	Object       string `json:"object"`
+	AttributedTo string `json:"attributedTo"`
+}
+
+/**
+ * Decode object of activity. Object which is only a link has only ID
+ */
+func DecodeObject(raw json.RawMessage) (Object, error) {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return Object{ID: id}, nil
+	}
+
+	var object struct {
+		Object
+		NestedObject json.RawMessage `json:"object"`
+	}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return Object{}, err
+	}
+	if object.ID == "" {
+		return Object{}, errors.New("object without id")
+	}
+	// Object of embedded activity is only needed as a link
+	if len(object.NestedObject) > 0 {
+		nested, err := DecodeObject(object.NestedObject)
+		if err != nil {
+			return Object{}, err
+		}
+		object.Object.Object = nested.ID
+	}
+	return object.Object, nil
+}
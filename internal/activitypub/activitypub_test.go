@@ -0,0 +1,187 @@
+package activitypub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	keyOnce    sync.Once
+	testKeyPEM string
+	testPubPEM string
+)
+
+func testKeys(t *testing.T) (string, string) {
+	t.Helper()
+	keyOnce.Do(func() {
+		var err error
+		testKeyPEM, testPubPEM, err = GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	return testKeyPEM, testPubPEM
+}
+
+func signedRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+	privatePEM, _ := testKeys(t)
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "https://chirpy.test/ap/users/1/inbox", strings.NewReader(body))
+	if err := Sign(req, []byte(body), "https://remote.test/ap/users/2#main-key", key); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, publicPEM := testKeys(t)
+	publicKey, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"type":"Follow"}`
+	tests := []struct {
+		name    string
+		modify  func(req *http.Request)
+		body    string
+		wantErr bool
+	}{
+		{name: "valid", body: body},
+		{name: "changed body", body: `{"type":"Delete"}`, wantErr: true},
+		{name: "changed path", body: body, modify: func(req *http.Request) { req.URL.Path = "/ap/users/3/inbox" }, wantErr: true},
+		{name: "old date", body: body, modify: func(req *http.Request) {
+			req.Header.Set("Date", time.Now().Add(-2*MaxClockSkew).UTC().Format(http.TimeFormat))
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, body)
+			if tt.modify != nil {
+				tt.modify(req)
+			}
+			sig, err := ParseSignature(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig.KeyID != "https://remote.test/ap/users/2#main-key" {
+				t.Errorf("keyId = %q", sig.KeyID)
+			}
+			err = Verify(req, []byte(tt.body), sig, publicKey, time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeObject(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Object
+	}{
+		{raw: `"https://chirpy.test/ap/users/1"`, want: Object{ID: "https://chirpy.test/ap/users/1"}},
+		{
+			raw:  `{"id":"https://remote.test/follows/1","type":"Follow","actor":"https://remote.test/ap/users/2","object":"https://chirpy.test/ap/users/1"}`,
+			want: Object{ID: "https://remote.test/follows/1", Type: "Follow", Actor: "https://remote.test/ap/users/2", Object: "https://chirpy.test/ap/users/1"},
+		},
+		{
+			raw:  `{"id":"https://remote.test/notes/1","type":"Note","attributedTo":"https://remote.test/ap/users/2","content":"<p>Hi</p>"}`,
+			want: Object{ID: "https://remote.test/notes/1", Type: "Note", AttributedTo: "https://remote.test/ap/users/2"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeObject(json.RawMessage(tt.raw))
+		if err != nil {
+			t.Fatalf("DecodeObject(%s) error = %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Errorf("DecodeObject(%s) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestLookupAndDeliver(t *testing.T) {
+	privatePEM, publicPEM := testKeys(t)
+	privateKey, _ := ParsePrivateKey(privatePEM)
+	publicKey, _ := ParsePublicKey(publicPEM)
+
+	var server *httptest.Server
+	delivered := make(chan error, 1)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/webfinger":
+			if r.URL.Query().Get("resource") != "acct:bob@"+r.Host {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(WebFinger{Links: []WebFingerLink{{Rel: "self", Type: ContentType, Href: server.URL + "/ap/users/2"}}})
+		case "/ap/users/2/inbox":
+			body, _ := io.ReadAll(r.Body)
+			sig, err := ParseSignature(r)
+			if err == nil {
+				err = Verify(r, body, sig, publicKey, time.Now())
+			}
+			delivered <- err
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(true)
+	host := strings.TrimPrefix(server.URL, "http://")
+	actor, err := client.LookupAccount(context.Background(), "@bob@"+host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actor != server.URL+"/ap/users/2" {
+		t.Errorf("LookupAccount() = %q", actor)
+	}
+
+	activity := Activity{ID: "https://chirpy.test/follows/1", Type: TypeFollow, Actor: "https://chirpy.test/ap/users/1", Object: json.RawMessage(`"` + actor + `"`)}
+	err = client.Deliver(context.Background(), server.URL+"/ap/users/2/inbox", activity, "https://chirpy.test/ap/users/1#main-key", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-delivered; err != nil {
+		t.Errorf("inbox rejected signature: %v", err)
+	}
+}
+
+func TestLookupInvalidAccount(t *testing.T) {
+	for _, account := range []string{"bob", "@bob@", "bob@host/path"} {
+		if _, err := NewClient(false).LookupAccount(context.Background(), account); err == nil {
+			t.Errorf("LookupAccount(%q) succeeded", account)
+		}
+	}
+}
+
+func TestContent(t *testing.T) {
+	body := "Hello <b>world</b> & friends\nsecond line"
+	content := HTMLContent(body)
+	if content != "<p>Hello &lt;b&gt;world&lt;/b&gt; &amp; friends<br>second line</p>" {
+		t.Errorf("HTMLContent() = %q", content)
+	}
+	if got := PlainText(content); got != body {
+		t.Errorf("PlainText(HTMLContent()) = %q, want %q", got, body)
+	}
+
+	remote := `<p>Hi <a href="https://remote.test/tags/go" class="hashtag">#<span>go</span></a></p><p>Bye<script>alert(1)</script></p>`
+	if got := PlainText(remote); got != "Hi #go\n\nBye" {
+		t.Errorf("PlainText() = %q", got)
+	}
+}
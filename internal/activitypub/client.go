@@ -0,0 +1,121 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Documents of other servers are small, bigger responses are refused
+	maxResponseBytes = 1 << 20
+	requestTimeout   = 10 * time.Second
+)
+
+/**
+ * Client fetches documents of other servers and delivers activities to their inboxes.
+ * AllowHTTP makes WebFinger use plain http, it is meant for instances on a local network
+ */
+type Client struct {
+	HTTP      *http.Client
+	AllowHTTP bool
+}
+
+func NewClient(allowHTTP bool) *Client {
+	return &Client{HTTP: &http.Client{Timeout: requestTimeout}, AllowHTTP: allowHTTP}
+}
+
+/**
+ * Fetch ActivityPub document into v
+ */
+func (client *Client) Fetch(ctx context.Context, uri string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", ContentType)
+	return client.doJSON(req, v)
+}
+
+/**
+ * Find actor URI of account user@host with WebFinger
+ */
+func (client *Client) LookupAccount(ctx context.Context, account string) (string, error) {
+	username, host, ok := strings.Cut(strings.TrimPrefix(account, "@"), "@")
+	if !ok || username == "" || host == "" || strings.ContainsAny(host, "/?#@") {
+		return "", fmt.Errorf("invalid account %s", account)
+	}
+
+	scheme := "https"
+	if client.AllowHTTP {
+		scheme = "http"
+	}
+	query := url.Values{"resource": {"acct:" + username + "@" + host}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/.well-known/webfinger?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", JRDContentType)
+
+	var finger WebFinger
+	if err := client.doJSON(req, &finger); err != nil {
+		return "", err
+	}
+	for _, link := range finger.Links {
+		if link.Rel == "self" && (link.Type == ContentType || strings.Contains(link.Type, "activitystreams")) {
+			return link.Href, nil
+		}
+	}
+	return "", fmt.Errorf("account %s has no ActivityPub actor", account)
+}
+
+/**
+ * Post activity to inbox, signed with the key of the sending actor
+ */
+func (client *Client) Deliver(ctx context.Context, inbox string, activity interface{}, keyID string, key *rsa.PrivateKey) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := Sign(req, body, keyID, key); err != nil {
+		return err
+	}
+
+	resp, err := client.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("deliver to %s: status %d", inbox, resp.StatusCode)
+	}
+	return nil
+}
+
+func (client *Client) doJSON(req *http.Request, v interface{}) error {
+	resp, err := client.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch %s: status %d", req.URL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
@@ -0,0 +1,50 @@
+package activitypub
+
+import (
+	"html"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+/**
+ * Convert plain text of chirp to HTML content of a note
+ */
+func HTMLContent(text string) string {
+	return "<p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "</p>"
+}
+
+/**
+ * Convert HTML content of a remote note to plain text. Markup is dropped, so remote content
+ * can't inject anything into clients, paragraphs and line breaks become new lines
+ */
+func PlainText(content string) string {
+	var text strings.Builder
+	// Text of script and style is not content
+	skip := false
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			return strings.TrimSpace(text.String())
+		case xhtml.TextToken:
+			if !skip {
+				text.Write(tokenizer.Text())
+			}
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			switch name, _ := tokenizer.TagName(); string(name) {
+			case "br":
+				text.WriteString("\n")
+			case "script", "style":
+				skip = true
+			}
+		case xhtml.EndTagToken:
+			switch name, _ := tokenizer.TagName(); string(name) {
+			case "p":
+				text.WriteString("\n\n")
+			case "script", "style":
+				skip = false
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	keyBits = 2048
+	// Signed requests with Date further from now are rejected, it limits replay of captured requests
+	MaxClockSkew = time.Hour
+)
+
+/**
+ * Parsed Signature header of draft-cavage-http-signatures, the version used by the fediverse
+ */
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Value     []byte
+}
+
+/**
+ * Generate RSA key pair of an actor, both keys are PEM encoded
+ */
+func GenerateKey() (privatePEM string, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", "", err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	return privatePEM, publicPEM, nil
+}
+
+func ParsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+/**
+ * Parse public key of an actor, PKIX and PKCS#1 encodings are both used in the wild
+ */
+func ParsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+/**
+ * Value of Digest header for the body
+ */
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+/**
+ * Sign request with the key of an actor. Date and for requests with body Digest are set too
+ */
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", Digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, headers)))
+	value, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(value)))
+	return nil
+}
+
+/**
+ * Parse Signature header of request
+ */
+func ParseSignature(req *http.Request) (Signature, error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return Signature{}, errors.New("missing Signature header")
+	}
+
+	sig := Signature{Headers: []string{"date"}}
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Signature{}, errors.New("invalid Signature header")
+		}
+		value = strings.Trim(value, `"`)
+		switch name {
+		case "keyId":
+			sig.KeyID = value
+		case "algorithm":
+			sig.Algorithm = value
+		case "headers":
+			sig.Headers = strings.Fields(strings.ToLower(value))
+		case "signature":
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return Signature{}, errors.New("invalid signature encoding")
+			}
+			sig.Value = decoded
+		}
+	}
+	if sig.KeyID == "" || len(sig.Value) == 0 {
+		return Signature{}, errors.New("incomplete Signature header")
+	}
+	return sig, nil
+}
+
+/**
+ * Verify signature of request with public key of the signer. Request target, host and date must be
+ * signed, requests with body must sign Digest which matches the body
+ */
+func Verify(req *http.Request, body []byte, sig Signature, key *rsa.PublicKey, now time.Time) error {
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return fmt.Errorf("unsupported algorithm %s", sig.Algorithm)
+	}
+
+	required := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		required = append(required, "digest")
+	}
+	for _, header := range required {
+		if !contains(sig.Headers, header) {
+			return fmt.Errorf("header %s is not signed", header)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return errors.New("invalid Date header")
+	}
+	if date.Before(now.Add(-MaxClockSkew)) || date.After(now.Add(MaxClockSkew)) {
+		return errors.New("Date is too far from now")
+	}
+
+	if body != nil && req.Header.Get("Digest") != Digest(body) {
+		return errors.New("Digest doesn't match body")
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, sig.Headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig.Value); err != nil {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		switch header {
+		case "(request-target)":
+			lines[i] = header + ": " + strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			lines[i] = header + ": " + host
+		default:
+			lines[i] = header + ": " + strings.Join(req.Header.Values(header), ", ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func contains(list []string, value string) bool {
+	for _, elem := range list {
+		if elem == value {
+			return true
+		}
+	}
+	return false
+}
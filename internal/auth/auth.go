@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -56,28 +57,78 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
  * Validate JWT token
  */
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateJWT(tokenString, tokenSecret, "chirpy")
+}
+
+/**
+ * Make short-lived token of login which waits for the second factor.
+ * It has own issuer, so it can't be used as access token. challengeID is the token id (jti),
+ * the server keeps the challenge, so the token works once
+ */
+func MakeMFAToken(userID, challengeID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy-mfa",
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+		ID:        challengeID.String(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(tokenSecret))
+}
+
+/**
+ * Validate token of login which waits for the second factor, returns the user and the challenge id
+ */
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, uuid.UUID, error) {
+	claims, err := parseJWT(tokenString, tokenSecret, "chirpy-mfa")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	return userID, challengeID, nil
+}
+
+func validateJWT(tokenString, tokenSecret, issuer string) (uuid.UUID, error) {
+	claims, err := parseJWT(tokenString, tokenSecret, issuer)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+func parseJWT(tokenString, tokenSecret, issuer string) (*jwt.RegisteredClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(tokenSecret), nil
-	})
+	}, jwt.WithIssuer(issuer))
 
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(*jwt.RegisteredClaims)
 	if !ok || !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token")
-	}
-
-	userID, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, err
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return userID, nil
+	return claims, nil
 }
 
 /**
@@ -124,4 +175,13 @@ func MakeRefreshToken() (string, error){
 		return "", err
 	}
 	return hex.EncodeToString(b), nil
+}
+
+/**
+ * Hash token which is stored in database instead of the token itself.
+ * Tokens are random, so fast hash is enough
+ */
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
\ No newline at end of file
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Secret of test vectors in RFC 6238 appendix B for SHA-1
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		wantOK bool
+	}{
+		{name: "current period", at: now, wantOK: true},
+		{name: "previous period", at: now.Add(-30 * time.Second), wantOK: true},
+		{name: "next period", at: now.Add(30 * time.Second), wantOK: true},
+		{name: "too old", at: now.Add(-90 * time.Second), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := TOTPCode(secret, tt.at)
+			counter, ok := ValidateTOTP(secret, code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && counter != tt.at.Unix()/30 {
+				t.Errorf("ValidateTOTP() counter = %d, want %d", counter, tt.at.Unix()/30)
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("ValidateTOTP() accepted short code")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Chirpy", "alice@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:alice@example.com?") {
+		t.Errorf("TOTPURI() = %s", uri)
+	}
+	for _, want := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Chirpy", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("TOTPURI() = %s, missing %s", uri, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 19 || seen[code] {
+			t.Errorf("bad or repeated code %q", code)
+		}
+		seen[code] = true
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", ""))
+		if NormalizeRecoveryCode(typed) != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, NormalizeRecoveryCode(typed), code)
+		}
+	}
+}
+
+func TestMFATokenIsNotAccessToken(t *testing.T) {
+	userID, challengeID := uuid.New(), uuid.New()
+	token, err := MakeMFAToken(userID, challengeID, "secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateJWT(token, "secret"); err == nil {
+		t.Error("ValidateJWT() accepted MFA token")
+	}
+	gotUser, gotChallenge, err := ValidateMFAToken(token, "secret")
+	if err != nil || gotUser != userID || gotChallenge != challengeID {
+		t.Errorf("ValidateMFAToken() = %v, %v, %v", gotUser, gotChallenge, err)
+	}
+
+	// Access token has no challenge id
+	access, _ := MakeJWT(userID, "secret", time.Minute)
+	if _, _, err := ValidateMFAToken(access, "secret"); err == nil {
+		t.Error("ValidateMFAToken() accepted access token")
+	}
+}
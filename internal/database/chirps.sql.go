@@ -7,23 +7,32 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, user_id, body)
-VALUES (gen_random_uuid(), now(), now(), $1, $2)
-Returning id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, user_id, body, in_reply_to, quote_of, rechirp_of, status, publish_at, visibility)
+VALUES (gen_random_uuid(), now(), now(), $1, $2, $3, $4, $5, $6, $7, $8)
+Returning id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
 `
 
 type CreateChirpParams struct {
-	UserID uuid.UUID
-	Body   string
+	UserID     uuid.UUID
+	Body       string
+	InReplyTo  uuid.NullUUID
+	QuoteOf    uuid.NullUUID
+	RechirpOf  uuid.NullUUID
+	Status     string
+	PublishAt  sql.NullTime
+	Visibility string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.UserID, arg.Body)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.UserID, arg.Body, arg.InReplyTo, arg.QuoteOf, arg.RechirpOf, arg.Status, arg.PublishAt, arg.Visibility)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -31,6 +40,16 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
 	)
 	return i, err
 }
@@ -45,7 +64,7 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps WHERE deleted_at IS NULL AND status = 'published' ORDER BY created_at
 `
 
 func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
@@ -63,6 +82,16 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -78,7 +107,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getAllChirpsDesc = `-- name: GetAllChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at DESC
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps WHERE deleted_at IS NULL AND status = 'published' ORDER BY created_at DESC
 `
 
 func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
@@ -96,6 +125,93 @@ func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.search_vector, parent.in_reply_to, parent.reply_count, parent.tombstoned_at, parent.quote_of, parent.rechirp_of, parent.status, parent.publish_at, parent.deleted_at, parent.visibility, 1 AS depth FROM chirps AS parent
+    WHERE parent.id = (SELECT c.in_reply_to FROM chirps AS c WHERE c.id = $1)
+    UNION ALL
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.search_vector, parent.in_reply_to, parent.reply_count, parent.tombstoned_at, parent.quote_of, parent.rechirp_of, parent.status, parent.publish_at, parent.deleted_at, parent.visibility, a.depth + 1 FROM chirps AS parent
+    JOIN ancestors AS a ON parent.id = a.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+FROM ancestors
+WHERE NOT is_blocked(user_id, $2::uuid)
+  AND can_read($2::uuid, user_id, id, visibility)
+ORDER BY depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+type GetChirpAncestorsRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector interface{}
+	InReplyTo    uuid.NullUUID
+	ReplyCount   int32
+	TombstonedAt sql.NullTime
+	QuoteOf      uuid.NullUUID
+	RechirpOf    uuid.NullUUID
+	Status       string
+	PublishAt    sql.NullTime
+	DeletedAt    sql.NullTime
+	Visibility   string
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -111,7 +227,7 @@ func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps WHERE id = $1
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -123,12 +239,103 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
 	)
 	return i, err
 }
 
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT reply.id, reply.created_at, reply.updated_at, reply.body, reply.user_id, reply.search_vector, reply.in_reply_to, reply.reply_count, reply.tombstoned_at, reply.quote_of, reply.rechirp_of, reply.status, reply.publish_at, reply.deleted_at, reply.visibility FROM chirps AS reply
+    WHERE reply.in_reply_to = $1 AND reply.status = 'published'
+    UNION ALL
+    SELECT reply.id, reply.created_at, reply.updated_at, reply.body, reply.user_id, reply.search_vector, reply.in_reply_to, reply.reply_count, reply.tombstoned_at, reply.quote_of, reply.rechirp_of, reply.status, reply.publish_at, reply.deleted_at, reply.visibility FROM chirps AS reply
+    JOIN descendants AS d ON reply.in_reply_to = d.id
+    WHERE reply.status = 'published'
+)
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+FROM descendants AS d
+WHERE (d.deleted_at IS NULL OR EXISTS (SELECT 1 FROM chirps AS reply WHERE reply.in_reply_to = d.id))
+  AND NOT is_hidden($2::uuid, d.user_id)
+  AND can_read($2::uuid, d.user_id, d.id, d.visibility)
+ORDER BY created_at, id
+LIMIT $3
+`
+
+type GetChirpDescendantsParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+	MaxRows  int32
+}
+
+type GetChirpDescendantsRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector interface{}
+	InReplyTo    uuid.NullUUID
+	ReplyCount   int32
+	TombstonedAt sql.NullTime
+	QuoteOf      uuid.NullUUID
+	RechirpOf    uuid.NullUUID
+	Status       string
+	PublishAt    sql.NullTime
+	DeletedAt    sql.NullTime
+	Visibility   string
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.ID, arg.ViewerID, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY $2
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps WHERE user_id = $1 AND deleted_at IS NULL AND status = 'published' ORDER BY $2
 `
 
 type GetChirpsByUserIDParams struct {
@@ -151,6 +358,16 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, arg GetChirpsByUserIDPa
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -165,11 +382,985 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, arg GetChirpsByUserIDPa
 	return items, nil
 }
 
-const resetAllChirps = `-- name: ResetAllChirps :exec
-DELETE FROM chirps
+const getChirpsPage = `-- name: GetChirpsPage :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE deleted_at IS NULL
+  AND status = 'published'
+  AND visibility <> 'unlisted'
+  AND NOT is_hidden($1::uuid, user_id)
+  AND can_read($1::uuid, user_id, id, visibility)
+  AND (created_at, id) > ($2::timestamp, $3::uuid)
+ORDER BY created_at, id
+LIMIT $4
 `
 
-func (q *Queries) ResetAllChirps(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, resetAllChirps)
-	return err
+type GetChirpsPageParams struct {
+	ViewerID        uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsPage(ctx context.Context, arg GetChirpsPageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPage, arg.ViewerID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPageByUserID = `-- name: GetChirpsPageByUserID :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND status = 'published'
+  AND NOT is_hidden($2::uuid, user_id)
+  AND can_read($2::uuid, user_id, id, visibility)
+  AND (created_at, id) > ($3::timestamp, $4::uuid)
+ORDER BY created_at, id
+LIMIT $5
+`
+
+type GetChirpsPageByUserIDParams struct {
+	UserID          uuid.UUID
+	ViewerID        uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsPageByUserID(ctx context.Context, arg GetChirpsPageByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageByUserID, arg.UserID, arg.ViewerID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPageByUserIDDesc = `-- name: GetChirpsPageByUserIDDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND status = 'published'
+  AND NOT is_hidden($2::uuid, user_id)
+  AND can_read($2::uuid, user_id, id, visibility)
+  AND (created_at, id) < ($3::timestamp, $4::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type GetChirpsPageByUserIDDescParams struct {
+	UserID          uuid.UUID
+	ViewerID        uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsPageByUserIDDesc(ctx context.Context, arg GetChirpsPageByUserIDDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageByUserIDDesc, arg.UserID, arg.ViewerID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPageDesc = `-- name: GetChirpsPageDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE deleted_at IS NULL
+  AND status = 'published'
+  AND visibility <> 'unlisted'
+  AND NOT is_hidden($1::uuid, user_id)
+  AND can_read($1::uuid, user_id, id, visibility)
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetChirpsPageDescParams struct {
+	ViewerID        uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsPageDesc(ctx context.Context, arg GetChirpsPageDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageDesc, arg.ViewerID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirps = `-- name: GetDeletedChirps :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE deleted_at IS NOT NULL
+  AND ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND (deleted_at, id) < ($2::timestamp, $3::uuid)
+ORDER BY deleted_at DESC, id DESC
+LIMIT $4
+`
+
+type GetDeletedChirpsParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetDeletedChirps(ctx context.Context, arg GetDeletedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedChirps, arg.AuthorID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirpsByUserID = `-- name: GetDeletedChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE user_id = $1
+  AND tombstoned_at IS NULL
+  AND deleted_at >= $2::timestamp
+  AND (deleted_at, id) < ($3::timestamp, $4::uuid)
+ORDER BY deleted_at DESC, id DESC
+LIMIT $5
+`
+
+type GetDeletedChirpsByUserIDParams struct {
+	UserID          uuid.UUID
+	DeletedSince    time.Time
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetDeletedChirpsByUserID(ctx context.Context, arg GetDeletedChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedChirpsByUserID, arg.UserID, arg.DeletedSince, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastChirpDeletion = `-- name: GetLastChirpDeletion :one
+SELECT max(c.deleted_at)::timestamp AS deleted_at FROM chirps AS c
+WHERE c.deleted_at IS NOT NULL
+  AND c.status = 'published'
+  AND ($1::uuid IS NULL OR c.user_id = $1::uuid)
+  AND ($2::text IS NULL OR EXISTS (
+      SELECT 1 FROM chirp_tags AS t WHERE t.chirp_id = c.id AND t.tag = lower($2::text)
+  ))
+`
+
+type GetLastChirpDeletionParams struct {
+	UserID uuid.NullUUID
+	Tag    sql.NullString
+}
+
+func (q *Queries) GetLastChirpDeletion(ctx context.Context, arg GetLastChirpDeletionParams) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getLastChirpDeletion, arg.UserID, arg.Tag)
+	var deleted_at sql.NullTime
+	err := row.Scan(&deleted_at)
+	return deleted_at, err
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.search_vector, c.in_reply_to, c.reply_count, c.tombstoned_at, c.quote_of, c.rechirp_of, c.status, c.publish_at, c.deleted_at, c.visibility FROM chirps AS c
+JOIN follows AS f ON f.followee_id = c.user_id
+WHERE f.follower_id = $1
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND NOT is_hidden($1, c.user_id)
+  AND can_read($1, c.user_id, c.id, c.visibility)
+  AND (c.created_at, c.id) < ($2::timestamp, $3::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline, arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnpublishedChirpsByUserID = `-- name: GetUnpublishedChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND status <> 'published'
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetUnpublishedChirpsByUserIDParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetUnpublishedChirpsByUserID(ctx context.Context, arg GetUnpublishedChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUnpublishedChirpsByUserID, arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpByID = `-- name: GetVisibleChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE id = $1
+  AND NOT is_blocked(user_id, $2::uuid)
+  AND can_read($2::uuid, user_id, id, visibility)
+`
+
+type GetVisibleChirpByIDParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirpByID(ctx context.Context, arg GetVisibleChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirpByID, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getVisibleChirpsByIDs = `-- name: GetVisibleChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility FROM chirps
+WHERE id = ANY($1::uuid[])
+  AND NOT is_blocked(user_id, $2::uuid)
+  AND can_read($2::uuid, user_id, id, visibility)
+`
+
+type GetVisibleChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirpsByIDs(ctx context.Context, arg GetVisibleChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getVisibleChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishChirp = `-- name: PublishChirp :one
+UPDATE chirps SET status = 'published', publish_at = NULL, created_at = now(), updated_at = now()
+WHERE id = $1 AND status <> 'published'
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+func (q *Queries) PublishChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, publishChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+UPDATE chirps SET status = 'published', publish_at = NULL, created_at = now(), updated_at = now()
+WHERE status = 'scheduled' AND id IN (
+    SELECT id FROM chirps
+    WHERE status = 'scheduled' AND publish_at <= now() AND deleted_at IS NULL
+    ORDER BY publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+func (q *Queries) PublishDueChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeExpiredChirps = `-- name: PurgeExpiredChirps :execrows
+DELETE FROM chirps AS c
+WHERE c.deleted_at < $1
+  AND NOT EXISTS (SELECT 1 FROM chirps AS reply WHERE reply.in_reply_to = c.id)
+`
+
+func (q *Queries) PurgeExpiredChirps(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeExpiredChirps, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetAllChirps = `-- name: ResetAllChirps :exec
+DELETE FROM chirps
+`
+
+func (q *Queries) ResetAllChirps(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetAllChirps)
+	return err
+}
+
+const restoreChirpByID = `-- name: RestoreChirpByID :one
+UPDATE chirps SET deleted_at = NULL
+WHERE id = $1 AND tombstoned_at IS NULL AND deleted_at >= $2::timestamp
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+type RestoreChirpByIDParams struct {
+	ID           uuid.UUID
+	DeletedSince time.Time
+}
+
+func (q *Queries) RestoreChirpByID(ctx context.Context, arg RestoreChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirpByID, arg.ID, arg.DeletedSince)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const scheduleChirp = `-- name: ScheduleChirp :one
+UPDATE chirps SET status = 'scheduled', publish_at = $2, updated_at = now()
+WHERE id = $1 AND status <> 'published'
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+type ScheduleChirpParams struct {
+	ID        uuid.UUID
+	PublishAt sql.NullTime
+}
+
+func (q *Queries) ScheduleChirp(ctx context.Context, arg ScheduleChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, scheduleChirp, arg.ID, arg.PublishAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.search_vector, c.in_reply_to, c.reply_count, c.tombstoned_at, c.quote_of, c.rechirp_of, c.status, c.publish_at, c.deleted_at, c.visibility, ts_rank(c.search_vector, q.query) AS rank,
+    ts_headline('english', c.body, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+FROM chirps AS c, websearch_to_tsquery('english', $1::text) AS q(query)
+WHERE c.search_vector @@ q.query
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND c.visibility <> 'unlisted'
+  AND NOT is_hidden($2::uuid, c.user_id)
+  AND can_read($2::uuid, c.user_id, c.id, c.visibility)
+  AND ($3::uuid IS NULL OR c.user_id = $3::uuid)
+  AND ($4::timestamp IS NULL OR c.created_at >= $4::timestamp)
+  AND ($5::timestamp IS NULL OR c.created_at < $5::timestamp)
+  AND (ts_rank(c.search_vector, q.query), c.created_at, c.id)
+    < ($6::real, $7::timestamp, $8::uuid)
+ORDER BY rank DESC, c.created_at DESC, c.id DESC
+LIMIT $9
+`
+
+type SearchChirpsParams struct {
+	Query           string
+	ViewerID        uuid.UUID
+	AuthorID        uuid.NullUUID
+	CreatedSince    sql.NullTime
+	CreatedUntil    sql.NullTime
+	CursorRank      float32
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
+}
+
+type SearchChirpsRow struct {
+	Chirp   Chirp
+	Rank    float32
+	Snippet string
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps, arg.Query, arg.ViewerID, arg.AuthorID, arg.CreatedSince, arg.CreatedUntil, arg.CursorRank, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.ReplyCount,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.Status,
+			&i.Chirp.PublishAt,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.Visibility,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteChirpByID = `-- name: SoftDeleteChirpByID :exec
+UPDATE chirps SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirpByID, id)
+	return err
+}
+
+const tombstoneExpiredChirps = `-- name: TombstoneExpiredChirps :execrows
+WITH revisions AS (
+    DELETE FROM chirp_revisions WHERE chirp_id IN (
+        SELECT id FROM chirps WHERE deleted_at < $1 AND tombstoned_at IS NULL
+    )
+)
+UPDATE chirps SET body = '', tombstoned_at = now()
+WHERE deleted_at < $1 AND tombstoned_at IS NULL
+`
+
+func (q *Queries) TombstoneExpiredChirps(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, tombstoneExpiredChirps, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unscheduleChirpsWithClosedPolls = `-- name: UnscheduleChirpsWithClosedPolls :many
+UPDATE chirps SET status = 'draft', publish_at = NULL, updated_at = now()
+WHERE status = 'scheduled' AND publish_at <= now() AND deleted_at IS NULL
+  AND EXISTS (SELECT 1 FROM polls WHERE polls.chirp_id = chirps.id AND polls.closes_at <= now())
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+func (q *Queries) UnscheduleChirpsWithClosedPolls(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, unscheduleChirpsWithClosedPolls)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unscheduleChirpsWithHiddenTargets = `-- name: UnscheduleChirpsWithHiddenTargets :many
+UPDATE chirps AS c SET status = 'draft', publish_at = NULL, updated_at = now()
+WHERE c.status = 'scheduled' AND c.publish_at <= now() AND c.deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM chirps AS t
+      WHERE t.id IN (c.in_reply_to, c.quote_of, c.rechirp_of)
+        AND (t.status <> 'published'
+          OR t.deleted_at IS NOT NULL
+          OR is_blocked(t.user_id, c.user_id)
+          OR NOT can_read(c.user_id, t.user_id, t.id, t.visibility)
+          OR (t.id IN (c.quote_of, c.rechirp_of) AND t.visibility NOT IN ('public', 'unlisted')))
+  )
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+func (q *Queries) UnscheduleChirpsWithHiddenTargets(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, unscheduleChirpsWithHiddenTargets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.TombstonedAt,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), c.id, c.body, c.updated_at, now() FROM chirps AS c WHERE c.id = $2
+)
+UPDATE chirps SET body = $1, updated_at = now()
+WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+`
+
+type UpdateChirpParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.TombstonedAt,
+		&i.QuoteOf,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.Visibility,
+	)
+	return i, err
 }
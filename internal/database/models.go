@@ -11,12 +11,195 @@ import (
 	"github.com/google/uuid"
 )
 
+type AccountToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Purpose   string
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type ActorKey struct {
+	UserID        uuid.UUID
+	PublicKeyPem  string
+	PrivateKeyPem string
+	CreatedAt     time.Time
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector interface{}
+	InReplyTo    uuid.NullUUID
+	ReplyCount   int32
+	TombstonedAt sql.NullTime
+	QuoteOf      uuid.NullUUID
+	RechirpOf    uuid.NullUUID
+	Status       string
+	PublishAt    sql.NullTime
+	DeletedAt    sql.NullTime
+	Visibility   string
+}
+
+type ChirpFlag struct {
+	ChirpID   uuid.UUID
+	Reasons   string
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type ChirpReaction struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Reaction  string
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type ChirpTag struct {
+	ChirpID uuid.UUID
+	Tag     string
+}
+
+type Conversation struct {
 	ID        uuid.UUID
+	CreatedBy uuid.UUID
+	DirectKey sql.NullString
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+}
+
+type ConversationParticipant struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Medium struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+	Position     int32
+	ContentType  string
+	Size         int64
+	Width        int32
+	Height       int32
+	BlobKey      string
+	ThumbnailKey string
+	CreatedAt    time.Time
+	IsAvatar     bool
+}
+
+type Message struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+}
+
+type MfaChallenge struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	Attempts  int32
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type ModerationWord struct {
+	Word      string
+	Action    string
+	CreatedAt time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Type      string
+	ActorID   uuid.UUID
+	ChirpID   uuid.NullUUID
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type PinnedChirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+	CreatedAt time.Time
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Position  int32
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +211,67 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type RemoteActor struct {
+	ID                uuid.UUID
+	Uri               string
+	KeyID             string
+	Inbox             string
+	PreferredUsername string
+	PublicKeyPem      string
+	FetchedAt         time.Time
+}
+
+type RemoteFollower struct {
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	FollowUri string
+	CreatedAt time.Time
+}
+
+type RemoteFollowing struct {
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	FollowUri string
+	Accepted  bool
+	CreatedAt time.Time
+}
+
+type RemoteLike struct {
+	ChirpID   uuid.UUID
+	ActorID   uuid.UUID
+	LikeUri   string
+	CreatedAt time.Time
+}
+
+type RemoteNote struct {
+	ID          uuid.UUID
+	Uri         string
+	ActorID     uuid.UUID
+	Content     string
+	Url         string
+	InReplyTo   uuid.NullUUID
+	PublishedAt time.Time
+	CreatedAt   time.Time
+}
+
 type User struct {
-	ID             uuid.UUID
-	Email          string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	HashedPassword  string
+	IsChirpyRed     sql.NullBool
+	Handle          sql.NullString
+	DisplayName     string
+	Bio             string
+	AvatarID        uuid.NullUUID
+	EmailVerifiedAt sql.NullTime
+}
+
+type UserTotp struct {
+	UserID      uuid.UUID
+	Secret      string
+	EnabledAt   sql.NullTime
+	LastCounter int64
+	CreatedAt   time.Time
 }
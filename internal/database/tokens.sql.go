@@ -39,7 +39,7 @@ func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (Refre
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT u.id, u.email, u.created_at, u.updated_at, u.hashed_password, u.is_chirpy_red FROM refresh_tokens as rt
+SELECT u.id, u.email, u.created_at, u.updated_at, u.hashed_password, u.is_chirpy_red, u.handle, u.display_name, u.bio, u.avatar_id, u.email_verified_at FROM refresh_tokens as rt
 JOIN users as u ON rt.user_id = u.id
 WHERE token = $1 AND expires_at > now() AND revoked_at IS NULL
 `
@@ -54,6 +54,11 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -67,3 +72,13 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
 	return err
 }
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
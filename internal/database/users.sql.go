@@ -8,23 +8,26 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), now(), now(), $1, $2)
-Returning id, email, created_at, updated_at, hashed_password, is_chirpy_red
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (gen_random_uuid(), now(), now(), $1, $2, $3)
+Returning id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_id, email_verified_at
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -33,12 +36,58 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
+const getAuthorsByIDs = `-- name: GetAuthorsByIDs :many
+SELECT u.id, u.handle, u.display_name, m.blob_key AS avatar_key FROM users AS u
+LEFT JOIN media AS m ON m.id = u.avatar_id
+WHERE u.id = ANY($1::uuid[])
+`
+
+type GetAuthorsByIDsRow struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	AvatarKey   sql.NullString
+}
+
+func (q *Queries) GetAuthorsByIDs(ctx context.Context, ids []uuid.UUID) ([]GetAuthorsByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAuthorsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAuthorsByIDsRow
+	for rows.Next() {
+		var i GetAuthorsByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.DisplayName,
+			&i.AvatarKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_id, email_verified_at FROM users WHERE lower(email) = lower($1::text)
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -51,12 +100,40 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_id, email_verified_at FROM users WHERE lower(handle) = lower($1::text)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red FROM users WHERE id = $1
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_id, email_verified_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -69,10 +146,117 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserHandlesByIDs = `-- name: GetUserHandlesByIDs :many
+SELECT id, handle FROM users WHERE id = ANY($1::uuid[])
+`
+
+type GetUserHandlesByIDsRow struct {
+	ID     uuid.UUID
+	Handle sql.NullString
+}
+
+func (q *Queries) GetUserHandlesByIDs(ctx context.Context, ids []uuid.UUID) ([]GetUserHandlesByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserHandlesByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserHandlesByIDsRow
+	for rows.Next() {
+		var i GetUserHandlesByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT u.id, u.handle, u.display_name, u.bio, m.blob_key AS avatar_key, u.created_at,
+    (SELECT count(*) FROM follows WHERE followee_id = u.id)
+        + (SELECT count(*) FROM remote_followers WHERE user_id = u.id) AS followers_count,
+    (SELECT count(*) FROM follows WHERE follower_id = u.id)
+        + (SELECT count(*) FROM remote_following WHERE user_id = u.id AND accepted) AS following_count,
+    (SELECT count(*) FROM chirps AS c
+     WHERE c.user_id = u.id AND c.deleted_at IS NULL AND c.status = 'published'
+       AND can_read($1::uuid, c.user_id, c.id, c.visibility)) AS chirps_count
+FROM users AS u
+LEFT JOIN media AS m ON m.id = u.avatar_id
+WHERE lower(u.handle) = lower($2::text)
+  AND NOT is_blocked(u.id, $1::uuid)
+`
+
+type GetUserProfileParams struct {
+	ViewerID uuid.UUID
+	Handle   string
+}
+
+type GetUserProfileRow struct {
+	ID             uuid.UUID
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarKey      sql.NullString
+	CreatedAt      time.Time
+	FollowersCount int64
+	FollowingCount int64
+	ChirpsCount    int64
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, arg GetUserProfileParams) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, arg.ViewerID, arg.Handle)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.CreatedAt,
+		&i.FollowersCount,
+		&i.FollowingCount,
+		&i.ChirpsCount,
 	)
 	return i, err
 }
 
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE users SET email_verified_at = now(), updated_at = now()
+WHERE id = $1 AND lower(email) = lower($2::text)
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const resetAllUsers = `-- name: ResetAllUsers :exec
 DELETE FROM users
 `
@@ -98,19 +282,73 @@ func (q *Queries) UpdateChirpyRedByUserID(ctx context.Context, arg UpdateChirpyR
 }
 
 const updateUser = `-- name: UpdateUser :one
-UPDATE users SET email = $1, hashed_password = $2, updated_at = now()
-WHERE id = $3
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+UPDATE users
+SET email = $1, hashed_password = $2,
+    handle = coalesce($3, handle), updated_at = now(),
+    email_verified_at = CASE WHEN lower(email) = lower($1::text) THEN email_verified_at END
+WHERE id = $4
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_id, email_verified_at
 `
 
 type UpdateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 	ID             uuid.UUID
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.Handle, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = $1, updated_at = now()
+WHERE id = $2
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = coalesce($1, handle), display_name = $2, bio = $3,
+    avatar_id = $4, updated_at = now()
+WHERE id = $5
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_id, email_verified_at
+`
+
+type UpdateUserProfileParams struct {
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+	AvatarID    uuid.NullUUID
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.Handle, arg.DisplayName, arg.Bio, arg.AvatarID, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -119,6 +357,11 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	TypeHashtag = "hashtag"
+	TypeMention = "mention"
+
+	MinHandleLength = 3
+	MaxHandleLength = 30
+	MaxTagLength    = 100
+)
+
+/**
+ * Hashtag or mention found in text. Start and End are offsets in runes,
+ * Start points to # or @, End points after the last char of entity
+ */
+type Entity struct {
+	Type  string
+	Text  string
+	Start int
+	End   int
+}
+
+/**
+ * Find hashtags and mentions in text. Entity must not be a part of other word,
+ * so e-mails are not mentions
+ */
+func Extract(text string) []Entity {
+	runes := []rune(text)
+	entities := []Entity{}
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' && runes[i] != '@' {
+			continue
+		}
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+
+		isTag := runes[i] == '#'
+		end := i + 1
+		for end < len(runes) && isEntityRune(runes[end], isTag) {
+			end++
+		}
+
+		name := string(runes[i+1 : end])
+		if isTag && validTag(name) {
+			entities = append(entities, Entity{Type: TypeHashtag, Text: name, Start: i, End: end})
+		}
+		if !isTag && IsHandle(name) && (end == len(runes) || !isWordRune(runes[end])) {
+			entities = append(entities, Entity{Type: TypeMention, Text: name, Start: i, End: end})
+		}
+		i = end - 1
+	}
+
+	return entities
+}
+
+/**
+ * Unique lowercase names of entities with the type
+ */
+func Names(entities []Entity, entityType string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, entity := range entities {
+		name := strings.ToLower(entity.Text)
+		if entity.Type != entityType || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+/**
+ * Check if string can be used as user handle
+ */
+func IsHandle(handle string) bool {
+	if len(handle) < MinHandleLength || len(handle) > MaxHandleLength {
+		return false
+	}
+	for _, r := range handle {
+		if !isHandleRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func validTag(tag string) bool {
+	if tag == "" || len([]rune(tag)) > MaxTagLength {
+		return false
+	}
+	// Only numbers is not a tag, e.g. #1
+	for _, r := range tag {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEntityRune(r rune, isTag bool) bool {
+	if isTag {
+		return isWordRune(r)
+	}
+	return isHandleRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Entity
+	}{
+		{
+			name: "Hashtag and mention",
+			text: "Hello @bob, see #golang!",
+			want: []Entity{
+				{Type: TypeMention, Text: "bob", Start: 6, End: 10},
+				{Type: TypeHashtag, Text: "golang", Start: 16, End: 23},
+			},
+		},
+		{
+			name: "Offsets in runes",
+			text: "Привет #мир",
+			want: []Entity{
+				{Type: TypeHashtag, Text: "мир", Start: 7, End: 11},
+			},
+		},
+		{
+			name: "E-mail is not a mention",
+			text: "write to bob@example.com",
+			want: []Entity{},
+		},
+		{
+			name: "Numbers only is not a hashtag",
+			text: "we are #1",
+			want: []Entity{},
+		},
+		{
+			name: "Too short handle",
+			text: "hi @al",
+			want: []Entity{},
+		},
+		{
+			name: "Handle followed by non ascii letter",
+			text: "hi @bobé",
+			want: []Entity{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	got := Names(Extract("#Go #go @Bob #rust @bob"), TypeHashtag)
+	want := []string{"go", "rust"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	// Chirp is visible to others: created as published, draft or scheduled chirp is published
+	ChirpPublished Type = "chirp.published"
+	ChirpDeleted   Type = "chirp.deleted"
+	ChirpReacted   Type = "chirp.reacted"
+	UserFollowed   Type = "user.followed"
+)
+
+/**
+ * Something has happened in the domain. Fields which are not related to the type are empty
+ */
+type Event struct {
+	Type     Type
+	ActorID  uuid.UUID
+	ChirpID  uuid.UUID
+	UserID   uuid.UUID
+	Reaction string
+}
+
+type Handler func(ctx context.Context, event Event) error
+
+/**
+ * Bus delivers events to subscribed handlers. Publishers don't know who listens,
+ * so a new reaction to an event doesn't change the code which emits it
+ */
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[Type][]Handler{}}
+}
+
+/**
+ * Subscribe handler to events of the type
+ */
+func (bus *Bus) Subscribe(eventType Type, handler Handler) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.handlers[eventType] = append(bus.handlers[eventType], handler)
+}
+
+/**
+ * Deliver event to all its handlers in order of subscription. Failed handler doesn't stop others,
+ * errors of all handlers are returned together
+ */
+func (bus *Bus) Publish(ctx context.Context, event Event) error {
+	bus.mu.RLock()
+	handlers := bus.handlers[event.Type]
+	bus.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
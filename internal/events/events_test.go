@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublishDeliversToSubscribers(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe(UserFollowed, func(ctx context.Context, event Event) error {
+		got = append(got, "first:"+event.UserID.String())
+		return nil
+	})
+	bus.Subscribe(UserFollowed, func(ctx context.Context, event Event) error {
+		got = append(got, "second:"+event.UserID.String())
+		return nil
+	})
+	bus.Subscribe(ChirpReacted, func(ctx context.Context, event Event) error {
+		got = append(got, "reacted")
+		return nil
+	})
+
+	userID := uuid.New()
+	err := bus.Publish(context.Background(), Event{Type: UserFollowed, UserID: userID})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	want := []string{"first:" + userID.String(), "second:" + userID.String()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Publish() delivered %v, want %v", got, want)
+	}
+}
+
+func TestPublishWithoutSubscribers(t *testing.T) {
+	bus := NewBus()
+	err := bus.Publish(context.Background(), Event{Type: ChirpPublished})
+	if err != nil {
+		t.Errorf("Publish() error = %v, want nil", err)
+	}
+}
+
+func TestPublishJoinsErrors(t *testing.T) {
+	bus := NewBus()
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	called := 0
+	bus.Subscribe(ChirpPublished, func(ctx context.Context, event Event) error {
+		called++
+		return errFirst
+	})
+	bus.Subscribe(ChirpPublished, func(ctx context.Context, event Event) error {
+		called++
+		return errSecond
+	})
+
+	err := bus.Publish(context.Background(), Event{Type: ChirpPublished})
+	if called != 2 {
+		t.Errorf("Publish() called %d handlers, want 2", called)
+	}
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("Publish() error = %v, want both handler errors", err)
+	}
+}
@@ -0,0 +1,258 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+const (
+	ContentTypeAtom = "application/atom+xml; charset=utf-8"
+	ContentTypeRSS  = "application/rss+xml; charset=utf-8"
+	ContentTypeJSON = "application/feed+json; charset=utf-8"
+)
+
+/**
+ * Feed in a format independent form. Links are absolute URLs, Updated is the latest change of items
+ */
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	FeedURL     string
+	Updated     time.Time
+	Items       []Item
+}
+
+/**
+ * Item of the feed, ID is a stable URL of the item. Content is plain text
+ */
+type Item struct {
+	ID          string
+	URL         string
+	Title       string
+	Content     string
+	Author      string
+	Published   time.Time
+	Updated     time.Time
+	Tags        []string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	URL         string
+	ContentType string
+	Size        int64
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+/**
+ * Render feed as Atom 1.0
+ */
+func Atom(feed Feed) ([]byte, error) {
+	doc := atomFeed{
+		ID:      feed.FeedURL,
+		Title:   feed.Title,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: feed.FeedURL, Type: "application/atom+xml"},
+			{Rel: "alternate", Href: feed.Link},
+		},
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: item.Author},
+			Links:     []atomLink{{Rel: "alternate", Href: item.URL}},
+			Content:   atomContent{Type: "text", Body: item.Content},
+		}
+		for _, attachment := range item.Attachments {
+			entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Href: attachment.URL, Type: attachment.ContentType, Length: attachment.Size})
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshalXML(doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+/**
+ * Render feed as RSS 2.0. RSS allows one enclosure, so only the first attachment is included
+ */
+func RSS(feed Feed) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			Description:   feed.Description,
+			Self:          atomLink{Rel: "self", Href: feed.FeedURL, Type: "application/rss+xml"},
+			LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, item := range feed.Items {
+		entry := rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: item.Content,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Categories:  item.Tags,
+		}
+		if len(item.Attachments) > 0 {
+			attachment := item.Attachments[0]
+			entry.Enclosure = &rssEnclosure{URL: attachment.URL, Length: attachment.Size, Type: attachment.ContentType}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, entry)
+	}
+	return marshalXML(doc)
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url"`
+	FeedURL     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonAuthor     `json:"authors"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []jsonAttachment `json:"attachments,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonAttachment struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size_in_bytes,omitempty"`
+}
+
+/**
+ * Render feed as JSON Feed 1.1. Items have no title, like microblog posts in the spec
+ */
+func JSON(feed Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.FeedURL,
+		Description: feed.Description,
+		Items:       []jsonItem{},
+	}
+	for _, item := range feed.Items {
+		entry := jsonItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			ContentText:   item.Content,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Authors:       []jsonAuthor{{Name: item.Author}},
+			Tags:          item.Tags,
+		}
+		for _, attachment := range item.Attachments {
+			entry.Attachments = append(entry.Attachments, jsonAttachment{URL: attachment.URL, MimeType: attachment.ContentType, Size: attachment.Size})
+		}
+		doc.Items = append(doc.Items, entry)
+	}
+	return json.Marshal(doc)
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
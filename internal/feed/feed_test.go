@@ -0,0 +1,121 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	published := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:       "Chirps of @alice",
+		Description: "Latest chirps of @alice",
+		Link:        "https://chirpy.test/api/chirps?author_id=1",
+		FeedURL:     "https://chirpy.test/api/users/1/feed.atom",
+		Updated:     published.Add(time.Hour),
+		Items: []Item{{
+			ID:        "https://chirpy.test/api/chirps/2",
+			URL:       "https://chirpy.test/api/chirps/2",
+			Title:     "Hello <world> & #go",
+			Content:   "Hello <world> & #go",
+			Author:    "@alice",
+			Published: published,
+			Updated:   published.Add(time.Hour),
+			Tags:      []string{"go"},
+			Attachments: []Attachment{
+				{URL: "https://chirpy.test/media/a.png", ContentType: "image/png", Size: 100},
+				{URL: "https://chirpy.test/media/b.png", ContentType: "image/png", Size: 200},
+			},
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc atomFeed
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Atom() is not valid XML: %v", err)
+	}
+	if doc.Updated != "2024-05-01T11:00:00Z" {
+		t.Errorf("feed updated = %q", doc.Updated)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.Content.Body != "Hello <world> & #go" || entry.Published != "2024-05-01T10:00:00Z" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if len(entry.Links) != 3 || entry.Links[2].Rel != "enclosure" || entry.Links[2].Length != 200 {
+		t.Errorf("entry links = %+v", entry.Links)
+	}
+	if !strings.Contains(string(body), `<feed xmlns="http://www.w3.org/2005/Atom">`) {
+		t.Errorf("Atom() has no namespace:\n%s", body)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc rssDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("RSS() is not valid XML: %v", err)
+	}
+	if len(doc.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.PubDate != "Wed, 01 May 2024 10:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+	if item.Enclosure == nil || item.Enclosure.URL != "https://chirpy.test/media/a.png" {
+		t.Errorf("enclosure = %+v, want the first attachment", item.Enclosure)
+	}
+	for _, want := range []string{`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">`, `<atom:link rel="self"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("RSS() doesn't contain %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	body, err := JSON(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["version"] != "https://jsonfeed.org/version/1.1" {
+		t.Errorf("version = %v", doc["version"])
+	}
+	items := doc["items"].([]interface{})
+	item := items[0].(map[string]interface{})
+	if item["content_text"] != "Hello <world> & #go" || len(item["attachments"].([]interface{})) != 2 {
+		t.Errorf("item = %v", item)
+	}
+}
+
+func TestJSONEmptyFeedHasItems(t *testing.T) {
+	feed := testFeed()
+	feed.Items = nil
+	body, err := JSON(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"items":[]`) {
+		t.Errorf("empty feed = %s", body)
+	}
+}
@@ -0,0 +1,122 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/**
+ * Plain text email to one recipient
+ */
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+/**
+ * Mailer sends emails, backends are SMTP server, log and directory with .eml files
+ */
+type Mailer interface {
+	Send(ctx context.Context, from string, msg Message) error
+}
+
+/**
+ * Check that address is a bare email address, e.g. without display name
+ */
+func ValidAddress(address string) bool {
+	parsed, err := mail.ParseAddress(address)
+	return err == nil && parsed.Address == address
+}
+
+/**
+ * Format message as RFC 5322 email with headers. Header values with new lines are refused,
+ * so user input can't add headers
+ */
+func Compose(from string, msg Message, now time.Time) ([]byte, error) {
+	if !ValidAddress(from) || !ValidAddress(msg.To) {
+		return nil, errors.New("invalid address")
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("invalid subject")
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	_, domain, _ := strings.Cut(from, "@")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	buf.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes(), nil
+}
+
+/**
+ * LogMailer writes emails to the log, it is meant for development
+ */
+type LogMailer struct {
+	Logger *log.Logger
+}
+
+func (mailer LogMailer) Send(ctx context.Context, from string, msg Message) error {
+	data, err := Compose(from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	logger := mailer.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("mail to %s:\n%s", msg.To, data)
+	return nil
+}
+
+/**
+ * FileMailer saves every email as .eml file in Dir
+ */
+type FileMailer struct {
+	Dir string
+}
+
+func (mailer FileMailer) Send(ctx context.Context, from string, msg Message) error {
+	now := time.Now()
+	data, err := Compose(from, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(mailer.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(mailer.Dir, name), data, 0o644)
+}
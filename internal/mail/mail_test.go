@@ -0,0 +1,143 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompose(t *testing.T) {
+	msg := Message{To: "alice@example.com", Subject: "Reset ✓", Body: "line one\nline two"}
+	data, err := Compose("chirpy@example.com", msg, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := string(data)
+	for _, want := range []string{
+		"From: chirpy@example.com\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: =?utf-8?q?Reset_=E2=9C=93?=\r\n",
+		"Date: Wed, 02 Jan 2030 03:04:05 +0000\r\n",
+		"\r\n\r\nline one\r\nline two\r\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Compose() = %q, missing %q", text, want)
+		}
+	}
+}
+
+func TestComposeRejectsInjection(t *testing.T) {
+	tests := []Message{
+		{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"},
+		{To: "Alice <alice@example.com>", Subject: "Hi"},
+		{To: "alice@example.com", Subject: "Hi\r\nBcc: eve@example.com"},
+	}
+	for _, msg := range tests {
+		if _, err := Compose("chirpy@example.com", msg, time.Now()); err == nil {
+			t.Errorf("Compose(%q, %q) succeeded", msg.To, msg.Subject)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	err := FileMailer{Dir: dir}.Send(context.Background(), "chirpy@example.com", Message{To: "alice@example.com", Subject: "Hi", Body: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		t.Fatalf("ReadDir() = %v, %v", entries, err)
+	}
+	data, _ := os.ReadFile(dir + "/" + entries[0].Name())
+	if !strings.Contains(string(data), "\r\n\r\ntoken\r\n") {
+		t.Errorf("saved mail = %q", data)
+	}
+}
+
+/**
+ * Minimal SMTP server which accepts one message, like a local mail catcher
+ */
+func smtpCatcher(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 catcher ready")
+
+		var envelope, data strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 catcher")
+			case strings.HasPrefix(command, "MAIL FROM"), strings.HasPrefix(command, "RCPT TO"):
+				envelope.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- envelope.String() + data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := smtpCatcher(t)
+
+	msg := Message{To: "alice@example.com", Subject: "Verify email", Body: "Your token is abc"}
+	err := SMTPMailer{Addr: addr}.Send(context.Background(), "chirpy@example.com", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-received:
+		for _, want := range []string{"MAIL FROM:<chirpy@example.com>", "RCPT TO:<alice@example.com>", "Subject: Verify email", "Your token is abc"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("received %q, missing %q", got, want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
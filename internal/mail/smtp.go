@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// Time for delivery of one email when context has no deadline
+const smtpTimeout = 30 * time.Second
+
+/**
+ * SMTPMailer delivers emails to SMTP server at Addr (host:port). STARTTLS is used when the server
+ * supports it, authentication only when Username is set. A local catcher like MailHog works without both
+ */
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+}
+
+func (mailer SMTPMailer) Send(ctx context.Context, from string, msg Message) error {
+	data, err := Compose(from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(mailer.Addr)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", mailer.Addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if mailer.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", mailer.Username, mailer.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,301 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// Thumbnail fits into square of this size
+	ThumbnailSize = 320
+	// Bigger images are rejected before decoding to protect from decompression bombs
+	MaxPixels = 40_000_000
+)
+
+var ErrUnsupportedType = errors.New("unsupported media type")
+var ErrInvalidImage = errors.New("invalid image")
+
+// Extensions of supported types, content type is detected from file content
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+/**
+ * Uploaded image ready to save: metadata is stripped and thumbnail is generated
+ */
+type Image struct {
+	ContentType   string
+	Ext           string
+	Data          []byte
+	Width         int
+	Height        int
+	Thumbnail     []byte
+	ThumbnailType string
+	ThumbnailExt  string
+}
+
+/**
+ * Detect type of uploaded file by content, strip EXIF and other metadata and make thumbnail
+ */
+func Process(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return Image{}, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, ErrInvalidImage
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrInvalidImage
+	}
+
+	result := Image{ContentType: contentType, Ext: ext}
+	switch contentType {
+	case "image/jpeg":
+		orientation := jpegOrientation(data)
+		if orientation > 1 {
+			// Rotation is kept only in EXIF, so pixels are rotated before EXIF is removed
+			img = applyOrientation(img, orientation)
+			var buf bytes.Buffer
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+			if err != nil {
+				return Image{}, err
+			}
+			result.Data = buf.Bytes()
+		} else {
+			result.Data, err = stripJPEG(data)
+		}
+	case "image/png":
+		result.Data, err = stripPNG(data)
+	default:
+		result.Data = data
+	}
+	if err != nil {
+		return Image{}, ErrInvalidImage
+	}
+
+	bounds := img.Bounds()
+	result.Width = bounds.Dx()
+	result.Height = bounds.Dy()
+
+	thumb := thumbnail(img, ThumbnailSize)
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+		result.ThumbnailType, result.ThumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		// PNG keeps transparency of PNG and GIF images
+		err = png.Encode(&buf, thumb)
+		result.ThumbnailType, result.ThumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	result.Thumbnail = buf.Bytes()
+
+	return result, nil
+}
+
+/**
+ * Scale image down to fit into size x size, small images are not upscaled
+ */
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width > height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+/**
+ * Remove segments with metadata from JPEG: EXIF and XMP (APP1), IPTC (APP13) and comments.
+ * Image data is copied as is, so quality is not lost
+ */
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, ErrInvalidImage
+		}
+		marker := data[pos+1]
+		// Start of scan, the rest of file is compressed image data
+		if marker == 0xDA {
+			return append(out, data[pos:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	return nil, ErrInvalidImage
+}
+
+/**
+ * Remove text and EXIF chunks from PNG
+ */
+func stripPNG(data []byte) ([]byte, error) {
+	const signatureLength = 8
+	if len(data) < signatureLength {
+		return nil, ErrInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:signatureLength]...)
+	pos := signatureLength
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+
+		switch string(data[pos+4 : pos+8]) {
+		case "tEXt", "zTXt", "iTXt", "eXIf", "tIME":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	return out, nil
+}
+
+/**
+ * Read orientation tag from EXIF of JPEG, 1 is normal orientation
+ */
+func jpegOrientation(data []byte) int {
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF && data[pos+1] != 0xDA {
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : end]
+		if data[pos+1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos = end
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+/**
+ * Rotate and flip image according to EXIF orientation
+ */
+func applyOrientation(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Orientations 5-8 swap width and height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			default:
+				dx, dy = x, y
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
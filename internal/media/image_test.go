@@ -0,0 +1,126 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+/**
+ * JPEG with APP1 EXIF segment which has only orientation tag
+ */
+func jpegWithOrientation(t *testing.T, img image.Image, orientation byte) []byte {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+
+	data := buf.Bytes()
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+func TestProcessJPEG(t *testing.T) {
+	data := jpegWithOrientation(t, testImage(600, 400), 1)
+
+	result, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentType != "image/jpeg" || result.Ext != ".jpg" {
+		t.Errorf("type = %s %s", result.ContentType, result.Ext)
+	}
+	if bytes.Contains(result.Data, []byte("Exif")) {
+		t.Error("EXIF is not stripped")
+	}
+	if len(result.Data) != len(data)-len("Exif\x00\x00")-26-4 {
+		t.Errorf("image data changed: %d bytes, original %d", len(result.Data), len(data))
+	}
+	if result.Width != 600 || result.Height != 400 {
+		t.Errorf("size = %dx%d, want 600x400", result.Width, result.Height)
+	}
+
+	thumb, _, err := image.DecodeConfig(bytes.NewReader(result.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumb.Width != ThumbnailSize || thumb.Height != 213 {
+		t.Errorf("thumbnail = %dx%d, want %dx213", thumb.Width, thumb.Height, ThumbnailSize)
+	}
+}
+
+func TestProcessJPEGOrientation(t *testing.T) {
+	// Orientation 6: camera was rotated, image must be turned 90 degrees clockwise
+	data := jpegWithOrientation(t, testImage(60, 40), 6)
+
+	result, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 40 || result.Height != 60 {
+		t.Errorf("size = %dx%d, want 40x60", result.Width, result.Height)
+	}
+	if bytes.Contains(result.Data, []byte("Exif")) {
+		t.Error("EXIF is not stripped")
+	}
+}
+
+func TestProcessPNG(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(10, 10))
+	data := buf.Bytes()
+
+	// Insert tEXt chunk after IHDR
+	text := []byte{0, 0, 0, 9, 't', 'E', 'X', 't', 'S', 'e', 'c', 'r', 'e', 't', 0, 'G', 'P'}
+	text = binary.BigEndian.AppendUint32(text, crc32.ChecksumIEEE(text[4:]))
+	ihdrEnd := 8 + 12 + 13
+	withText := append(append(append([]byte{}, data[:ihdrEnd]...), text...), data[ihdrEnd:]...)
+
+	result, err := Process(withText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(result.Data, data) {
+		t.Error("tEXt chunk is not stripped")
+	}
+	if result.ThumbnailType != "image/png" {
+		t.Errorf("thumbnail type = %s, want image/png", result.ThumbnailType)
+	}
+}
+
+func TestProcessUnsupported(t *testing.T) {
+	_, err := Process([]byte("<html><script>alert(1)</script></html>"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("error = %v, want ErrUnsupportedType", err)
+	}
+
+	_, err = Process([]byte("\xFF\xD8\xFF\xE0 broken"))
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("error = %v, want ErrInvalidImage", err)
+	}
+}
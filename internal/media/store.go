@@ -0,0 +1,92 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var ErrNotFound = errors.New("blob not found")
+var ErrInvalidKey = errors.New("invalid blob key")
+
+/**
+ * Storage of uploaded files. Key is a flat file name like "<uuid>.jpg"
+ */
+type BlobStore interface {
+	Put(ctx context.Context, key string, contentType string, data io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+/**
+ * Keys come from urls, so only plain file names are allowed
+ */
+func ValidKey(key string) bool {
+	return len(key) <= 200 && keyPattern.MatchString(key)
+}
+
+/**
+ * Blobs saved as files in directory on local filesystem
+ */
+type LocalStore struct {
+	Dir string
+}
+
+func (store LocalStore) Put(ctx context.Context, key string, contentType string, data io.Reader) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := os.MkdirAll(store.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, so readers never see half written blob
+	tmp, err := os.CreateTemp(store.Dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(store.Dir, key))
+}
+
+func (store LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	file, err := os.Open(filepath.Join(store.Dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (store LocalStore) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := os.Remove(filepath.Join(store.Dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
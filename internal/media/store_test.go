@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+
+	err := store.Put(ctx, "blob.txt", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	blob, err := store.Get(ctx, "blob.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(blob)
+	blob.Close()
+	if string(data) != "hello" {
+		t.Errorf("Get = %q, want %q", data, "hello")
+	}
+
+	err = store.Delete(ctx, "blob.txt")
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = store.Get(ctx, "blob.txt")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+
+	err = store.Put(ctx, "../escape.txt", "text/plain", strings.NewReader("x"))
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put with path error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, LocalStore{Dir: t.TempDir()})
+}
+
+/**
+ * Minimal stand-in of S3 which keeps objects in memory and checks signatures
+ */
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		s3.t.Errorf("%s %s: wrong payload hash", r.Method, r.URL.Path)
+	}
+
+	// Sign the same request again and compare signatures
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		s3.t.Errorf("%s %s: invalid X-Amz-Date", r.Method, r.URL.Path)
+	}
+	check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	signV4(check, body, "us-east-1", "access", "secret", now)
+	if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s3.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := s3.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(s3.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{t: t, objects: map[string][]byte{}})
+	defer server.Close()
+
+	testStore(t, S3Store{
+		Endpoint:  server.URL,
+		Bucket:    "media",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+}
+
+func TestS3StoreWrongSecret(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{t: t, objects: map[string][]byte{}})
+	defer server.Close()
+
+	store := S3Store{Endpoint: server.URL, Bucket: "media", Region: "us-east-1", AccessKey: "access", SecretKey: "wrong"}
+	err := store.Put(context.Background(), "blob.txt", "text/plain", bytes.NewReader([]byte("hello")))
+	if err == nil {
+		t.Error("Put with wrong secret succeeded")
+	}
+}
+
+func TestFileServer(t *testing.T) {
+	store := LocalStore{Dir: t.TempDir()}
+	store.Put(context.Background(), "image.png", "image/png", strings.NewReader("png"))
+
+	server := FileServer(store)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/image.png", nil))
+	if rec.Code != 200 || rec.Body.String() != "png" || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("GET /image.png = %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/missing.png", nil))
+	if rec.Code != 404 {
+		t.Errorf("GET /missing.png = %d, want 404", rec.Code)
+	}
+}
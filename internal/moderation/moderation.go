@@ -0,0 +1,98 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+/**
+ * What to do with text which matches a filter
+ */
+type Action string
+
+const (
+	ActionAllow   Action = "allow"
+	ActionReplace Action = "replace"
+	ActionFlag    Action = "flag"
+	ActionReject  Action = "reject"
+)
+
+// Replacement of matched text
+const Mask = "****"
+
+/**
+ * Result of moderation. Text is the text after replacements,
+ * Reasons describe every match of flag or reject filters
+ */
+type Result struct {
+	Action  Action
+	Text    string
+	Reasons []string
+}
+
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Result, error)
+}
+
+/**
+ * Chain of moderators. Every moderator gets the text after replacements of
+ * the previous one, the most strict action wins. Reject stops the chain
+ */
+type Chain []Moderator
+
+func (chain Chain) Moderate(ctx context.Context, text string) (Result, error) {
+	result := Result{Action: ActionAllow, Text: text}
+	for _, moderator := range chain {
+		step, err := moderator.Moderate(ctx, result.Text)
+		if err != nil {
+			return Result{}, err
+		}
+
+		result.Text = step.Text
+		result.Reasons = append(result.Reasons, step.Reasons...)
+		if severity(step.Action) > severity(result.Action) {
+			result.Action = step.Action
+		}
+		if result.Action == ActionReject {
+			break
+		}
+	}
+	return result, nil
+}
+
+/**
+ * Check if action is known
+ */
+func ValidAction(action Action) bool {
+	return action == ActionReplace || action == ActionFlag || action == ActionReject
+}
+
+/**
+ * Normalize text for matching: compatibility decomposition, without diacritics, lower case.
+ * So "Ｋérfuffle" and "kerfuffle" are the same
+ */
+func Normalize(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(text) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func severity(action Action) int {
+	switch action {
+	case ActionReplace:
+		return 1
+	case ActionFlag:
+		return 2
+	case ActionReject:
+		return 3
+	}
+	return 0
+}
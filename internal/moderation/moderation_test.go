@@ -0,0 +1,115 @@
+package moderation
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var badWords = StaticWords{
+	{Text: "kerfuffle", Action: ActionReplace},
+	{Text: "sharbert", Action: ActionReplace},
+	{Text: "fornax", Action: ActionReplace},
+	{Text: "spamword", Action: ActionFlag},
+	{Text: "forbidden", Action: ActionReject},
+}
+
+func TestWordFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantText   string
+		wantAction Action
+	}{
+		{
+			name:       "Clean text",
+			text:       "I had something interesting for breakfast",
+			wantText:   "I had something interesting for breakfast",
+			wantAction: ActionAllow,
+		},
+		{
+			name:       "Replace word",
+			text:       "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			wantText:   "I hear Mastodon is better than Chirpy. **** I need to migrate",
+			wantAction: ActionReplace,
+		},
+		{
+			name:       "Word with punctuation",
+			text:       "What a Kerfuffle!",
+			wantText:   "What a ****!",
+			wantAction: ActionReplace,
+		},
+		{
+			name:       "Word with diacritics and full width letters",
+			text:       "ｆｏｒｎａｘ and kérfuffle",
+			wantText:   "**** and ****",
+			wantAction: ActionReplace,
+		},
+		{
+			name:       "Part of other word is fine",
+			text:       "kerfuffled",
+			wantText:   "kerfuffled",
+			wantAction: ActionAllow,
+		},
+		{
+			name:       "Flag keeps text",
+			text:       "buy spamword now",
+			wantText:   "buy spamword now",
+			wantAction: ActionFlag,
+		},
+		{
+			name:       "Reject is the strictest",
+			text:       "kerfuffle forbidden",
+			wantText:   "**** forbidden",
+			wantAction: ActionReject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WordFilter{Source: badWords}.Moderate(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("Moderate() error = %v", err)
+			}
+			if got.Text != tt.wantText || got.Action != tt.wantAction {
+				t.Errorf("Moderate() = %q %v, want %q %v", got.Text, got.Action, tt.wantText, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestIsWord(t *testing.T) {
+	for _, word := range []string{"fornax", "Kérfuffle", "abc123"} {
+		if !IsWord(word) {
+			t.Errorf("IsWord(%q) = false", word)
+		}
+	}
+	for _, word := range []string{"", "two words", "don't", "spam.example", "bad!"} {
+		if IsWord(word) {
+			t.Errorf("IsWord(%q) = true", word)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{
+		WordFilter{Source: badWords},
+		RegexFilter{Pattern: regexp.MustCompile(`https?://spam\.example\S*`), Action: ActionFlag},
+		RegexFilter{Pattern: regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`), Action: ActionReplace},
+	}
+
+	got, err := chain.Moderate(context.Background(), "fornax visit http://spam.example/x card 1234-5678-9012-3456")
+	if err != nil {
+		t.Fatalf("Moderate() error = %v", err)
+	}
+
+	want := Result{
+		Action:  ActionFlag,
+		Text:    "**** visit http://spam.example/x card ****",
+		Reasons: []string{`rule "https?://spam\\.example\\S*"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Moderate() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/**
+ * Matches text with regular expression. Matches are replaced with Mask for replace action
+ */
+type RegexFilter struct {
+	Pattern *regexp.Regexp
+	Action  Action
+}
+
+func (filter RegexFilter) Moderate(ctx context.Context, text string) (Result, error) {
+	if !filter.Pattern.MatchString(text) {
+		return Result{Action: ActionAllow, Text: text}, nil
+	}
+
+	if filter.Action == ActionReplace {
+		return Result{Action: ActionReplace, Text: filter.Pattern.ReplaceAllString(text, Mask)}, nil
+	}
+
+	return Result{
+		Action:  filter.Action,
+		Text:    text,
+		Reasons: []string{fmt.Sprintf("rule %q", filter.Pattern.String())},
+	}, nil
+}
+
+/**
+ * Load regex filters from file, one rule per line in format "action pattern".
+ * Empty lines and lines started with # are skipped
+ */
+func LoadRegexFilters(path string) ([]RegexFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	filters := []RegexFilter{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, " ", 2)
+		if len(parts) != 2 || !ValidAction(Action(parts[0])) {
+			return nil, fmt.Errorf("%s:%d: expected \"action pattern\"", path, line)
+		}
+
+		pattern, err := regexp.Compile(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		filters = append(filters, RegexFilter{Pattern: pattern, Action: Action(parts[0])})
+	}
+
+	return filters, scanner.Err()
+}
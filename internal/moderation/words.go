@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+type Word struct {
+	Text   string
+	Action Action
+}
+
+/**
+ * Source of words for WordFilter: a file, database table etc.
+ */
+type WordSource interface {
+	Words(ctx context.Context) ([]Word, error)
+}
+
+/**
+ * Fixed list of words
+ */
+type StaticWords []Word
+
+func (words StaticWords) Words(ctx context.Context) ([]Word, error) {
+	return words, nil
+}
+
+/**
+ * Words from text file, one word per line in format "word" or "word,action".
+ * Empty lines and lines started with # are skipped
+ */
+type FileWords struct {
+	Path string
+}
+
+func (source FileWords) Words(ctx context.Context) ([]Word, error) {
+	file, err := os.Open(source.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	words := []Word{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		word := Word{Text: text, Action: ActionReplace}
+		if parts := strings.SplitN(text, ",", 2); len(parts) == 2 {
+			word = Word{Text: strings.TrimSpace(parts[0]), Action: Action(strings.TrimSpace(parts[1]))}
+		}
+		if !ValidAction(word.Action) {
+			return nil, fmt.Errorf("%s:%d: unknown action %q", source.Path, line, word.Action)
+		}
+		words = append(words, word)
+	}
+
+	return words, scanner.Err()
+}
+
+/**
+ * Keeps words of other source in memory for TTL
+ */
+type CachedWords struct {
+	Source WordSource
+	TTL    time.Duration
+
+	mu       sync.Mutex
+	words    []Word
+	loadedAt time.Time
+}
+
+func (cache *CachedWords) Words(ctx context.Context) ([]Word, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.words != nil && time.Since(cache.loadedAt) < cache.TTL {
+		return cache.words, nil
+	}
+
+	words, err := cache.Source.Words(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cache.words = words
+	cache.loadedAt = time.Now()
+	return words, nil
+}
+
+/**
+ * Drop cached words, next call loads them from source
+ */
+func (cache *CachedWords) Invalidate() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.words = nil
+}
+
+/**
+ * Matches whole words of text with words from source. Words are compared after
+ * Normalize, punctuation around a word doesn`t matter
+ */
+type WordFilter struct {
+	Source WordSource
+}
+
+func (filter WordFilter) Moderate(ctx context.Context, text string) (Result, error) {
+	words, err := filter.Source.Words(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	actions := make(map[string]Action, len(words))
+	for _, word := range words {
+		actions[Normalize(word.Text)] = word.Action
+	}
+
+	result := Result{Action: ActionAllow}
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		i = end
+
+		action, ok := actions[Normalize(word)]
+		if !ok {
+			b.WriteString(word)
+			continue
+		}
+
+		if severity(action) > severity(result.Action) {
+			result.Action = action
+		}
+		if action == ActionReplace {
+			b.WriteString(Mask)
+			continue
+		}
+		result.Reasons = append(result.Reasons, fmt.Sprintf("word %q", word))
+		b.WriteString(word)
+	}
+
+	result.Text = b.String()
+	return result, nil
+}
+
+/**
+ * Check if text is one word as WordFilter splits text, other words can never match
+ */
+func IsWord(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
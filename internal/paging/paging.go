@@ -0,0 +1,104 @@
+package paging
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+/**
+ * Position in a list ordered by (created_at, id). Search results are
+ * ordered by (rank, created_at, id), so Rank is set only for them
+ */
+type Cursor struct {
+	Rank      float32
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+/**
+ * Cursor that is placed before the first row of a list
+ */
+func Start(desc bool) Cursor {
+	if desc {
+		return Cursor{
+			Rank:      math.MaxFloat32,
+			CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+			ID:        uuid.Max,
+		}
+	}
+	return Cursor{
+		CreatedAt: time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Nil,
+	}
+}
+
+/**
+ * Encode cursor to opaque string for clients
+ */
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID.String() + ":" +
+		strconv.FormatFloat(float64(c.Rank), 'g', -1, 32)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+/**
+ * Decode cursor which was made by Encode
+ */
+func Decode(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	micro, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	rank, err := strconv.ParseFloat(parts[2], 32)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	return Cursor{Rank: float32(rank), CreatedAt: time.UnixMicro(micro).UTC(), ID: id}, nil
+}
+
+/**
+ * Parse limit query parameter. Empty value gives DefaultLimit
+ */
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid limit")
+	}
+
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit, nil
+}
@@ -0,0 +1,79 @@
+package paging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{
+			name:   "Regular cursor",
+			cursor: Cursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()},
+		},
+		{
+			name:   "Ranked cursor",
+			cursor: Cursor{Rank: 0.0607927, CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), ID: uuid.New()},
+		},
+		{
+			name:   "Start asc",
+			cursor: Start(false),
+		},
+		{
+			name:   "Start desc",
+			cursor: Start(true),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Rank != tt.cursor.Rank || !got.CreatedAt.Equal(tt.cursor.CreatedAt) || got.ID != tt.cursor.ID {
+				t.Errorf("Decode() = %v, want %v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "MTIz", "YWJjOjEyMw"} {
+		if _, err := Decode(s); err == nil {
+			t.Errorf("Decode(%q) expected error", s)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   string
+		want    int
+		wantErr bool
+	}{
+		{name: "Empty", limit: "", want: DefaultLimit},
+		{name: "Regular", limit: "10", want: 10},
+		{name: "Too big", limit: "1000", want: MaxLimit},
+		{name: "Zero", limit: "0", wantErr: true},
+		{name: "Not a number", limit: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
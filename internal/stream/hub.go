@@ -0,0 +1,135 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Size of the buffer of every subscription. Subscriber which falls behind more than this is dropped
+const subscriptionBuffer = 64
+
+/**
+ * Message pushed to subscribers of any of its channels. AuthorID and Visibility are not sent
+ * to clients, subscribers use them to filter messages they are not allowed to see.
+ * Message published by reference has only Event and Ref, e.g. id of chirp, the rest is loaded on delivery
+ */
+type Message struct {
+	Event      string          `json:"event"`
+	Ref        string          `json:"ref,omitempty"`
+	Channels   []string        `json:"channels"`
+	AuthorID   uuid.UUID       `json:"author_id"`
+	Visibility string          `json:"visibility,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+/**
+ * Publisher delivers message to subscribers, on this instance or on all of them
+ */
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type Filter func(msg Message) bool
+
+/**
+ * Subscription receives messages of its channels from C. C is closed when subscription is closed
+ * or when subscriber is too slow to read
+ */
+type Subscription struct {
+	C        <-chan Message
+	messages chan Message
+	channels map[string]bool
+	filter   Filter
+	hub      *Hub
+	closed   bool
+}
+
+/**
+ * Close subscription, it's safe to call it more than once
+ */
+func (sub *Subscription) Close() {
+	sub.hub.remove(sub)
+}
+
+/**
+ * Hub fans out messages to subscriptions of this instance
+ */
+type Hub struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[*Subscription]struct{}{}}
+}
+
+/**
+ * Subscribe to channels. Filter is called for every message of the channels, nil filter accepts all
+ */
+func (hub *Hub) Subscribe(channels []string, filter Filter) *Subscription {
+	messages := make(chan Message, subscriptionBuffer)
+	sub := &Subscription{
+		C:        messages,
+		messages: messages,
+		channels: make(map[string]bool, len(channels)),
+		filter:   filter,
+		hub:      hub,
+	}
+	for _, channel := range channels {
+		sub.channels[channel] = true
+	}
+
+	hub.mu.Lock()
+	hub.subs[sub] = struct{}{}
+	hub.mu.Unlock()
+	return sub
+}
+
+/**
+ * Deliver message to subscriptions of any of its channels, every subscription gets it once.
+ * Publish never blocks: subscription with full buffer is closed, client reconnects and reloads
+ */
+func (hub *Hub) Publish(ctx context.Context, msg Message) error {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for sub := range hub.subs {
+		if !sub.matches(msg) {
+			continue
+		}
+		select {
+		case sub.messages <- msg:
+		default:
+			hub.close(sub)
+		}
+	}
+	return nil
+}
+
+func (sub *Subscription) matches(msg Message) bool {
+	for _, channel := range msg.Channels {
+		if sub.channels[channel] {
+			return sub.filter == nil || sub.filter(msg)
+		}
+	}
+	return false
+}
+
+func (hub *Hub) remove(sub *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.close(sub)
+}
+
+// Must be called with locked mu
+func (hub *Hub) close(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(hub.subs, sub)
+	close(sub.messages)
+}
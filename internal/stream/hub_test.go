@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func receive(t *testing.T, sub *Subscription) (Message, bool) {
+	t.Helper()
+	select {
+	case msg, ok := <-sub.C:
+		return msg, ok
+	default:
+		return Message{}, false
+	}
+}
+
+func TestPublishDeliversToChannelSubscribers(t *testing.T) {
+	hub := NewHub()
+	global := hub.Subscribe([]string{"global"}, nil)
+	both := hub.Subscribe([]string{"global", "tag:go"}, nil)
+	other := hub.Subscribe([]string{"tag:rust"}, nil)
+
+	hub.Publish(context.Background(), Message{Event: "chirp.created", Channels: []string{"global", "tag:go"}})
+
+	if msg, ok := receive(t, global); !ok || msg.Event != "chirp.created" {
+		t.Errorf("global subscriber got %v, %v", msg, ok)
+	}
+	if _, ok := receive(t, both); !ok {
+		t.Errorf("subscriber of both channels got nothing")
+	}
+	if _, ok := receive(t, both); ok {
+		t.Errorf("subscriber of both channels got message twice")
+	}
+	if _, ok := receive(t, other); ok {
+		t.Errorf("subscriber of other channel got message")
+	}
+}
+
+func TestPublishAppliesFilter(t *testing.T) {
+	hub := NewHub()
+	hidden := uuid.New()
+	sub := hub.Subscribe([]string{"global"}, func(msg Message) bool {
+		return msg.AuthorID != hidden
+	})
+
+	hub.Publish(context.Background(), Message{Event: "chirp.created", Channels: []string{"global"}, AuthorID: hidden})
+	hub.Publish(context.Background(), Message{Event: "chirp.created", Channels: []string{"global"}, AuthorID: uuid.New()})
+
+	if len(sub.C) != 1 {
+		t.Errorf("filtered subscription has %d messages, want 1", len(sub.C))
+	}
+}
+
+func TestSlowSubscriberIsClosed(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe([]string{"global"}, nil)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(context.Background(), Message{Event: "chirp.created", Channels: []string{"global"}})
+	}
+
+	count := 0
+	for range sub.C {
+		count++
+	}
+	if count != subscriptionBuffer {
+		t.Errorf("slow subscriber got %d messages, want %d", count, subscriptionBuffer)
+	}
+	// Closing dropped subscription again must not panic
+	sub.Close()
+}
+
+func TestBridgeDeliversNotificationToHub(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe([]string{"user:1"}, nil)
+	bridge := &PGBridge{Hub: hub}
+
+	payload, _ := json.Marshal(Message{Event: "notification.created", Channels: []string{"user:1"}, Data: json.RawMessage(`{"id":1}`)})
+	bridge.deliver(context.Background(), string(payload))
+	bridge.deliver(context.Background(), "not json")
+
+	msg, ok := receive(t, sub)
+	if !ok || string(msg.Data) != `{"id":1}` {
+		t.Errorf("bridge delivered %v, %v", msg, ok)
+	}
+	if _, ok := receive(t, sub); ok {
+		t.Errorf("bridge delivered invalid payload")
+	}
+}
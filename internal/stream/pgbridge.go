@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// Postgres channel shared by all instances
+	notifyChannel = "chirpy_stream"
+	// Postgres rejects NOTIFY payload of 8000 bytes and more, large data is published by reference
+	maxNotifyPayload = 7999
+
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	listenerPingInterval = 90 * time.Second
+)
+
+/**
+ * Loader builds full message from message published by reference. Message which is gone
+ * before delivery is skipped when ok is false
+ */
+type Loader func(ctx context.Context, ref Message) (msg Message, ok bool, err error)
+
+/**
+ * PGBridge keeps hubs of all instances in sync: messages are published with Postgres NOTIFY
+ * and every instance, this one too, delivers them to its hub when LISTEN receives them.
+ * Messages with Ref are loaded by Load on every instance, so size of NOTIFY payload doesn't depend on data
+ */
+type PGBridge struct {
+	DB      *sql.DB
+	ConnStr string
+	Hub     *Hub
+	Load    Loader
+}
+
+func (bridge *PGBridge) Publish(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("stream message %s is too large: %d bytes", msg.Event, len(payload))
+	}
+
+	_, err = bridge.DB.ExecContext(ctx, "SELECT pg_notify($1, $2)", notifyChannel, string(payload))
+	return err
+}
+
+/**
+ * Listen for messages of all instances and deliver them to the hub until ctx is done.
+ * Messages published while connection to Postgres is lost are missed
+ */
+func (bridge *PGBridge) Listen(ctx context.Context) error {
+	listener := pq.NewListener(bridge.ConnStr, listenerMinReconnect, listenerMaxReconnect, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("stream listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ping.C:
+			go listener.Ping()
+		case notification := <-listener.Notify:
+			// nil is sent after reconnect
+			if notification == nil {
+				continue
+			}
+			bridge.deliver(ctx, notification.Extra)
+		}
+	}
+}
+
+func (bridge *PGBridge) deliver(ctx context.Context, payload string) {
+	var msg Message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("stream listener: invalid message: %v", err)
+		return
+	}
+
+	if msg.Ref != "" && bridge.Load != nil {
+		loaded, ok, err := bridge.Load(ctx, msg)
+		if err != nil {
+			log.Printf("stream listener: load %s %s: %v", msg.Event, msg.Ref, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		msg = loaded
+	}
+	bridge.Hub.Publish(ctx, msg)
+}
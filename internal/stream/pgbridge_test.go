@@ -0,0 +1,49 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDeliverLoadsMessagesByRef(t *testing.T) {
+	full := Message{Event: "chirp.created", Channels: []string{"global"}, Data: json.RawMessage(`{"body":"long"}`)}
+
+	tests := []struct {
+		name  string
+		msg   Message
+		load  Loader
+		want  bool
+		event string
+	}{
+		{name: "full message", msg: Message{Event: "notification.created", Channels: []string{"global"}}, want: true, event: "notification.created"},
+		{name: "loaded by ref", msg: Message{Event: "chirp.created", Ref: "1"}, want: true, event: "chirp.created",
+			load: func(ctx context.Context, ref Message) (Message, bool, error) { return full, true, nil }},
+		// Chirp was purged before delivery
+		{name: "gone", msg: Message{Event: "chirp.created", Ref: "1"},
+			load: func(ctx context.Context, ref Message) (Message, bool, error) { return Message{}, false, nil }},
+		{name: "load failed", msg: Message{Event: "chirp.created", Ref: "1"},
+			load: func(ctx context.Context, ref Message) (Message, bool, error) { return full, true, errors.New("db is down") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bridge := &PGBridge{Hub: NewHub(), Load: tt.load}
+			sub := bridge.Hub.Subscribe([]string{"global"}, nil)
+			payload, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bridge.deliver(context.Background(), string(payload))
+			msg, ok := receive(t, sub)
+			if ok != tt.want {
+				t.Fatalf("delivered = %v, want %v", ok, tt.want)
+			}
+			if ok && msg.Event != tt.event {
+				t.Errorf("event = %q, want %q", msg.Event, tt.event)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestServeSSE(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeSSE(w, r, hub.Subscribe([]string{"global"}, nil))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	hub.Publish(context.Background(), Message{Event: "chirp.created", Channels: []string{"global"}, Data: json.RawMessage(`{"id":"1"}`)})
+
+	reader := bufio.NewReader(resp.Body)
+	event, _ := reader.ReadString('\n')
+	data, _ := reader.ReadString('\n')
+	if event != "event: chirp.created\n" {
+		t.Errorf("event line = %q", event)
+	}
+	want := `data: {"event":"chirp.created","channels":["global"],"data":{"id":"1"}}` + "\n"
+	if data != want {
+		t.Errorf("data line = %q, want %q", data, want)
+	}
+}
+
+func TestServeWebSocket(t *testing.T) {
+	hub := NewHub()
+	subscribed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub := hub.Subscribe([]string{"tag:go"}, nil)
+		close(subscribed)
+		ServeWebSocket(w, r, sub)
+	}))
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	<-subscribed
+
+	hub.Publish(context.Background(), Message{Event: "chirp.deleted", Channels: []string{"tag:go"}, Data: json.RawMessage(`{"id":"1"}`)})
+
+	var got clientEvent
+	if err := websocket.JSON.Receive(conn, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != "chirp.deleted" || string(got.Data) != `{"id":"1"}` {
+		t.Errorf("received %+v", got)
+	}
+}
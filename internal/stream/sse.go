@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Comment sent when nothing happens, so proxies don't close idle connection
+const heartbeatInterval = 25 * time.Second
+
+/**
+ * Event sent to clients: name of the event, channels where it was published and its data
+ */
+type clientEvent struct {
+	Event    string          `json:"event"`
+	Channels []string        `json:"channels"`
+	Data     json.RawMessage `json:"data"`
+}
+
+func toClientEvent(msg Message) clientEvent {
+	return clientEvent{Event: msg.Event, Channels: msg.Channels, Data: msg.Data}
+}
+
+/**
+ * Write messages of subscription as Server-Sent Events until client goes away or subscription is closed
+ */
+func ServeSSE(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	defer sub.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(toClientEvent(msg))
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
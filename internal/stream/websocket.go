@@ -0,0 +1,51 @@
+package stream
+
+import (
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+/**
+ * Upgrade request to WebSocket and send messages of subscription as JSON text frames until client
+ * goes away or subscription is closed. Messages from client are ignored. Origin is not checked:
+ * access token is not a cookie, so other sites can't use it
+ */
+func ServeWebSocket(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	server := websocket.Server{
+		Handler: func(conn *websocket.Conn) {
+			defer sub.Close()
+			writeMessages(conn, sub)
+		},
+	}
+	server.ServeHTTP(w, r)
+	// Failed handshake never calls the handler
+	sub.Close()
+}
+
+func writeMessages(conn *websocket.Conn, sub *Subscription) {
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var ignored string
+		for {
+			if err := websocket.Message.Receive(conn, &ignored); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(conn, toClientEvent(msg)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package textlen
+
+import (
+	"regexp"
+
+	"github.com/rivo/uniseg"
+)
+
+// Every link has the same weight regardless of its real length
+const DefaultURLWeight = 23
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s]+`)
+
+/**
+ * URLs in text which are counted with own weight
+ */
+func URLs(text string) []string {
+	return urlPattern.FindAllString(text, -1)
+}
+
+/**
+ * Length of text as user sees it: count of grapheme clusters, so emoji with
+ * modifiers or letters with combining marks are one character.
+ * Every URL counts as urlWeight characters
+ */
+func Length(text string, urlWeight int) int {
+	length := 0
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		length += uniseg.GraphemeClusterCount(text[last:loc[0]]) + urlWeight
+		last = loc[1]
+	}
+
+	return length + uniseg.GraphemeClusterCount(text[last:])
+}
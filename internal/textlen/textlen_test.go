@@ -0,0 +1,41 @@
+package textlen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "ASCII", text: "Hello world", want: 11},
+		{name: "Cyrillic", text: "Привет мир", want: 10},
+		{name: "Emoji", text: strings.Repeat("🔥", 50), want: 50},
+		{name: "Emoji with skin tone", text: "👍🏽", want: 1},
+		{name: "Family emoji", text: "👨‍👩‍👧", want: 1},
+		{name: "Combining mark", text: "e\u0301", want: 1},
+		{name: "URL", text: "see https://example.com/a/very/long/path?with=query", want: 4 + DefaultURLWeight},
+		{name: "Two URLs", text: "http://a.io and HTTPS://b.io", want: 5 + 2*DefaultURLWeight},
+		{name: "Not URL", text: "ftp://example.com", want: 17},
+		{name: "Empty", text: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Length(tt.text, DefaultURLWeight)
+			if got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLs(t *testing.T) {
+	got := URLs("see http://a.io and HTTPS://b.io/path, not ftp://c.io")
+	if len(got) != 2 || got[0] != "http://a.io" || got[1] != "HTTPS://b.io/path," {
+		t.Errorf("URLs() = %q", got)
+	}
+}
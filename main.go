@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/St5/goboot-srv/internal/activitypub"
 	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/St5/goboot-srv/internal/mail"
+	"github.com/St5/goboot-srv/internal/media"
+	"github.com/St5/goboot-srv/internal/moderation"
+	"github.com/St5/goboot-srv/internal/stream"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -14,8 +24,29 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	conn           *sql.DB
 	tokenSecret    string
 	PolkaKey       string
+	reactionTypes  []string
+	adminKey       string
+	moderator      moderation.Moderator
+	// Words from database are cached, admin handlers reset the cache
+	moderationWords *moderation.CachedWords
+	blobs           media.BlobStore
+	maxUploadBytes  int64
+	// Domain events emitted by handlers, notifications are created by subscribers
+	events *events.Bus
+	// Subscriptions of this instance and publisher which reaches subscriptions of all instances
+	hub    *stream.Hub
+	stream stream.Publisher
+	// Absolute URL of the server for links in feeds, taken from request when it is empty.
+	// Federation needs it, ActivityPub endpoints are disabled without it
+	publicURL string
+	// Client for other ActivityPub servers, objects are federated only when publicURL is set
+	federation *activitypub.Client
+	// Emails with tokens for password reset and email verification
+	mailer   mail.Mailer
+	mailFrom string
 }
 
 func main() {
@@ -37,17 +68,70 @@ func main() {
 	conf := apiConfig{
 		fileserverHits: atomic.Int32{},
 		db:             database.New(db),
+		conn:           db,
 		tokenSecret:    secretToken,
 		PolkaKey:       PolkaKey,
+		reactionTypes:  parseReactionTypes(os.Getenv("REACTION_TYPES")),
+		adminKey:       os.Getenv("ADMIN_KEY"),
+		blobs:          newBlobStore(),
+		maxUploadBytes: parseMaxUploadBytes(os.Getenv("MEDIA_MAX_BYTES")),
+		events:         events.NewBus(),
+		publicURL:      strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+		federation:     activitypub.NewClient(os.Getenv("FEDERATION_ALLOW_HTTP") == "true"),
+		mailer:         newMailer(),
+		mailFrom:       os.Getenv("MAIL_FROM"),
 	}
+	if conf.mailFrom == "" {
+		conf.mailFrom = "chirpy@localhost"
+	}
+	if conf.publicURL == "" {
+		log.Printf("PUBLIC_URL is not set: password reset, email verification and federation are disabled")
+	}
+	conf.subscribeNotifications(conf.events)
+	conf.subscribeFederation(conf.events)
+
+	bridge := &stream.PGBridge{DB: db, ConnStr: dbUrl, Hub: stream.NewHub(), Load: conf.loadStreamMessage}
+	conf.hub = bridge.Hub
+	conf.stream = bridge
+	conf.subscribeStream(conf.events)
+
+	conf.moderationWords = &moderation.CachedWords{Source: dbWords{db: conf.db}, TTL: time.Minute}
+	conf.moderator, err = newModerator(conf.moderationWords, os.Getenv("MODERATION_WORDS_FILE"), os.Getenv("MODERATION_RULES_FILE"))
+	if err != nil {
+		panic(err)
+	}
+
+	go conf.runPublisher(context.Background(), publishInterval)
+	go conf.runPurger(context.Background(), purgeInterval)
+	go func() {
+		if err := bridge.Listen(context.Background()); err != nil {
+			log.Printf("stream listener stopped: %v", err)
+		}
+	}()
 
 	mux := http.NewServeMux()
 	mux.Handle("/app/", conf.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir("./public/")))))
 
+	mux.Handle("/media/", http.StripPrefix("/media", media.FileServer(conf.blobs)))
+
 	mux.HandleFunc("GET /admin/metrics", conf.hadlerMetrics)
 
 	mux.HandleFunc("POST /admin/reset", conf.handlerReset)
 
+	mux.HandleFunc("GET /admin/moderation/words", conf.middlewareAdmin(conf.handleGetModerationWords))
+
+	mux.HandleFunc("POST /admin/moderation/words", conf.middlewareAdmin(conf.handleCreateModerationWord))
+
+	mux.HandleFunc("DELETE /admin/moderation/words/{word}", conf.middlewareAdmin(conf.handleDeleteModerationWord))
+
+	mux.HandleFunc("GET /admin/moderation/flags", conf.middlewareAdmin(conf.handleGetFlaggedChirps))
+
+	mux.HandleFunc("DELETE /admin/moderation/flags/{chirpID}", conf.middlewareAdmin(conf.handleDeleteChirpFlag))
+
+	mux.HandleFunc("GET /admin/chirps/deleted", conf.middlewareAdmin(conf.handleAdminGetDeletedChirps))
+
+	mux.HandleFunc("GET /admin/chirps/{chirpID}", conf.middlewareAdmin(conf.handleAdminGetChirp))
+
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		//w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(200)
@@ -60,8 +144,48 @@ func main() {
 
 	mux.HandleFunc("PUT /api/users", conf.handleUpdateUser)
 
+	mux.HandleFunc("PUT /api/users/profile", conf.handleUpdateProfile)
+
+	mux.HandleFunc("GET /api/users/{handle}", conf.handleGetProfile)
+
+	mux.HandleFunc("POST /api/users/{userID}/follow", conf.handleFollowUser)
+
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", conf.handleUnfollowUser)
+
+	mux.HandleFunc("GET /api/users/{userID}/followers", conf.handleGetFollowers)
+
+	mux.HandleFunc("GET /api/users/{userID}/following", conf.handleGetFollowing)
+
+	mux.HandleFunc("POST /api/users/{userID}/block", conf.handleBlockUser)
+
+	mux.HandleFunc("DELETE /api/users/{userID}/block", conf.handleUnblockUser)
+
+	mux.HandleFunc("POST /api/users/{userID}/mute", conf.handleMuteUser)
+
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", conf.handleUnmuteUser)
+
+	mux.HandleFunc("GET /api/users/{userID}/likes", conf.handleGetUserLikes)
+
+	mux.HandleFunc("GET /api/users/{userID}/mentions", conf.handleGetUserMentions)
+
 	mux.HandleFunc("POST /api/login", conf.handleLogin)
 
+	mux.HandleFunc("POST /api/login/2fa", conf.handleLoginTwoFactor)
+
+	mux.HandleFunc("POST /api/users/2fa/setup", conf.handleSetupTwoFactor)
+
+	mux.HandleFunc("POST /api/users/2fa/verify", conf.handleVerifyTwoFactor)
+
+	mux.HandleFunc("DELETE /api/users/2fa", conf.handleDisableTwoFactor)
+
+	mux.HandleFunc("POST /api/password/forgot", conf.handleForgotPassword)
+
+	mux.HandleFunc("POST /api/password/reset", conf.handleResetPassword)
+
+	mux.HandleFunc("POST /api/email/verification", conf.handleRequestEmailVerification)
+
+	mux.HandleFunc("POST /api/email/verify", conf.handleVerifyEmail)
+
 	mux.HandleFunc("POST /api/refresh", conf.handRefresh)
 
 	mux.HandleFunc("POST /api/revoke", conf.handleRevoke)
@@ -70,18 +194,126 @@ func main() {
 
 	mux.HandleFunc("POST /api/chirps", conf.handleCreateChirp)
 
+	mux.HandleFunc("POST /api/media", conf.handleUploadMedia)
+
+	mux.HandleFunc("POST /api/drafts", conf.handleCreateDraft)
+
+	mux.HandleFunc("GET /api/drafts", conf.handleGetDrafts)
+
+	mux.HandleFunc("POST /api/drafts/{chirpID}/publish", conf.handlePublishDraft)
+
 	mux.HandleFunc("GET /api/chirps", conf.handleGetAllChirps)
 
+	mux.HandleFunc("GET /api/timeline", conf.handleGetTimeline)
+
+	mux.HandleFunc("POST /api/conversations", conf.handleCreateConversation)
+	mux.HandleFunc("GET /api/conversations", conf.handleGetConversations)
+	mux.HandleFunc("GET /api/conversations/{conversationID}", conf.handleGetConversation)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", conf.handleCreateMessage)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", conf.handleGetMessages)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", conf.handleMarkConversationRead)
+
+	mux.HandleFunc("GET /api/notifications", conf.handleGetNotifications)
+	mux.HandleFunc("GET /api/notifications/unread", conf.handleGetUnreadNotifications)
+	mux.HandleFunc("POST /api/notifications/read", conf.handleMarkAllNotificationsRead)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", conf.handleMarkNotificationRead)
+	mux.HandleFunc("GET /api/notifications/preferences", conf.handleGetNotificationPreferences)
+	mux.HandleFunc("PUT /api/notifications/preferences", conf.handleUpdateNotificationPreferences)
+
+	mux.HandleFunc("GET /api/limits", conf.handleGetLimits)
+
+	mux.HandleFunc("GET /api/chirps/search", conf.handleSearchChirps)
+
 	mux.HandleFunc("GET /api/chirps/{chirpID}", conf.handleGetChirp)
 
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", conf.handleUpdateChirp)
+
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", conf.handleDeleteChirp)
 
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", conf.handleRestoreChirp)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", conf.handleCreatePollVote)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", conf.handleCreateBookmark)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", conf.handleDeleteBookmark)
+	mux.HandleFunc("GET /api/bookmarks", conf.handleGetBookmarks)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", conf.handlePinChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", conf.handleUnpinChirp)
+
+	mux.HandleFunc("GET /api/trash", conf.handleGetTrash)
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", conf.handleGetChirpRevisions)
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", conf.handleGetChirpThread)
+
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", conf.handleGetTagChirps)
+
+	//Feeds
+
+	mux.HandleFunc("GET /api/feed.atom", conf.handleGetGlobalFeed)
+
+	mux.HandleFunc("GET /api/feed.rss", conf.handleGetGlobalFeed)
+
+	mux.HandleFunc("GET /api/feed.json", conf.handleGetGlobalFeed)
+
+	mux.HandleFunc("GET /api/users/{userID}/feed.atom", conf.handleGetUserFeed)
+
+	mux.HandleFunc("GET /api/users/{userID}/feed.rss", conf.handleGetUserFeed)
+
+	mux.HandleFunc("GET /api/users/{userID}/feed.json", conf.handleGetUserFeed)
+
+	mux.HandleFunc("GET /api/tags/{tag}/feed.atom", conf.handleGetTagFeed)
+
+	mux.HandleFunc("GET /api/tags/{tag}/feed.rss", conf.handleGetTagFeed)
+
+	mux.HandleFunc("GET /api/tags/{tag}/feed.json", conf.handleGetTagFeed)
+
+	//Reactions
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reactions", conf.handleCreateReaction)
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/reactions", conf.handleDeleteReaction)
+
+	//Stream
+
+	mux.HandleFunc("GET /api/stream", conf.handleStream)
+
+	mux.HandleFunc("GET /api/stream/ws", conf.handleStreamWebSocket)
+
+	//Federation
+
+	mux.HandleFunc("GET /.well-known/webfinger", conf.handleWebFinger)
+
+	mux.HandleFunc("GET /ap/users/{userID}", conf.handleGetActor)
+
+	mux.HandleFunc("GET /ap/users/{userID}/outbox", conf.handleGetOutbox)
+
+	mux.HandleFunc("GET /ap/users/{userID}/followers", conf.handleGetFollowersCollection)
+
+	mux.HandleFunc("POST /ap/users/{userID}/inbox", conf.handleInbox)
+
+	mux.HandleFunc("GET /ap/chirps/{chirpID}", conf.handleGetNote)
+
+	mux.HandleFunc("POST /api/federation/following", conf.handleFollowRemote)
+
+	mux.HandleFunc("GET /api/federation/following", conf.handleGetRemoteFollowing)
+
+	mux.HandleFunc("DELETE /api/federation/following/{actorID}", conf.handleUnfollowRemote)
+
+	mux.HandleFunc("GET /api/federation/timeline", conf.handleGetRemoteTimeline)
+
 	//Webhooks
 
 	mux.HandleFunc("POST /api/polka/webhooks", conf.handleWebhook)
 
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8585"
+	}
+
 	server := &http.Server{
-		Addr:    ":8585",
+		Addr:    ":" + port,
 		Handler: mux,
 	}
 
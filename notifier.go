@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/St5/goboot-srv/internal/database"
+	"github.com/St5/goboot-srv/internal/events"
+	"github.com/google/uuid"
+)
+
+// Types of notifications, every type can be disabled in preferences of the user
+const (
+	notificationReply    = "reply"
+	notificationMention  = "mention"
+	notificationQuote    = "quote"
+	notificationRechirp  = "rechirp"
+	notificationFollow   = "follow"
+	notificationReaction = "reaction"
+)
+
+var notificationTypes = []string{
+	notificationReply,
+	notificationMention,
+	notificationQuote,
+	notificationRechirp,
+	notificationFollow,
+	notificationReaction,
+}
+
+/**
+ * Subscribe notifications to domain events
+ */
+func (cfg *apiConfig) subscribeNotifications(bus *events.Bus) {
+	bus.Subscribe(events.ChirpPublished, cfg.notifyChirpPublished)
+	bus.Subscribe(events.UserFollowed, cfg.notifyUserFollowed)
+	bus.Subscribe(events.ChirpReacted, cfg.notifyChirpReacted)
+}
+
+/**
+ * Emit domain event. Handlers run after the change is saved, their errors don't fail the request
+ */
+func (cfg *apiConfig) emit(ctx context.Context, event events.Event) {
+	if cfg.events == nil {
+		return
+	}
+	if err := cfg.events.Publish(ctx, event); err != nil {
+		log.Printf("handle event %s: %v", event.Type, err)
+	}
+}
+
+/**
+ * Create notifications for users. Actor is never notified about own actions, users who blocked or muted
+ * the actor, disabled the type or can't read the chirp are skipped. Created notifications are pushed to the stream
+ */
+func (cfg *apiConfig) notify(ctx context.Context, notificationType string, actorID uuid.UUID, chirpID uuid.NullUUID, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	created, err := cfg.db.CreateNotifications(ctx, database.CreateNotificationsParams{
+		Type:    notificationType,
+		ActorID: actorID,
+		ChirpID: chirpID,
+		UserIds: userIDs,
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.streamNotifications(ctx, created)
+}
+
+/**
+ * Notify authors of replied, quoted and rechirped chirps and mentioned users
+ */
+func (cfg *apiConfig) notifyChirpPublished(ctx context.Context, event events.Event) error {
+	chirp, err := cfg.db.GetChirpByID(ctx, event.ChirpID)
+	if err != nil {
+		return err
+	}
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+
+	targets := []struct {
+		notificationType string
+		ref              uuid.NullUUID
+	}{
+		{notificationReply, chirp.InReplyTo},
+		{notificationQuote, chirp.QuoteOf},
+		{notificationRechirp, chirp.RechirpOf},
+	}
+	for _, target := range targets {
+		if !target.ref.Valid {
+			continue
+		}
+		referenced, err := cfg.db.GetChirpByID(ctx, target.ref.UUID)
+		if err != nil {
+			return err
+		}
+		err = cfg.notify(ctx, target.notificationType, chirp.UserID, chirpID, referenced.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	mentions, err := cfg.db.GetMentionsByChirpIDs(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return err
+	}
+	mentioned := make([]uuid.UUID, len(mentions))
+	for i, mention := range mentions {
+		mentioned[i] = mention.UserID
+	}
+	return cfg.notify(ctx, notificationMention, chirp.UserID, chirpID, mentioned...)
+}
+
+func (cfg *apiConfig) notifyUserFollowed(ctx context.Context, event events.Event) error {
+	return cfg.notify(ctx, notificationFollow, event.ActorID, uuid.NullUUID{}, event.UserID)
+}
+
+func (cfg *apiConfig) notifyChirpReacted(ctx context.Context, event events.Event) error {
+	chirp, err := cfg.db.GetChirpByID(ctx, event.ChirpID)
+	if err != nil {
+		return err
+	}
+	return cfg.notify(ctx, notificationReaction, event.ActorID, uuid.NullUUID{UUID: chirp.ID, Valid: true}, chirp.UserID)
+}
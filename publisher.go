@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/events"
+)
+
+const (
+	// How often publisher looks for due scheduled chirps
+	publishInterval = 10 * time.Second
+	// Max number of chirps published by one query
+	publishBatchSize = 100
+)
+
+/**
+ * Publish scheduled chirps when their publish_at comes. Rows are locked with SKIP LOCKED,
+ * so several server instances can run publisher at the same time without publishing a chirp twice
+ */
+func (cfg *apiConfig) runPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cfg.publishDueChirps(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) {
+	// Poll of a chirp published late would be closed already, the chirp goes back to drafts
+	unscheduled, err := cfg.db.UnscheduleChirpsWithClosedPolls(ctx)
+	if err != nil {
+		log.Printf("unschedule chirps with closed polls: %v", err)
+		return
+	}
+	for _, chirp := range unscheduled {
+		log.Printf("chirp %s is moved to drafts, its poll closed before publish", chirp.ID)
+	}
+
+	// The same checks as for new chirp, replied, quoted or rechirped chirp could be deleted or hidden since scheduling
+	unscheduled, err = cfg.db.UnscheduleChirpsWithHiddenTargets(ctx)
+	if err != nil {
+		log.Printf("unschedule chirps with hidden targets: %v", err)
+		return
+	}
+	for _, chirp := range unscheduled {
+		log.Printf("chirp %s is moved to drafts, the chirp it references is gone", chirp.ID)
+	}
+
+	for {
+		published, err := cfg.db.PublishDueChirps(ctx, publishBatchSize)
+		if err != nil {
+			log.Printf("publish scheduled chirps: %v", err)
+			return
+		}
+		for _, chirp := range published {
+			cfg.emit(ctx, events.Event{Type: events.ChirpPublished, ActorID: chirp.UserID, ChirpID: chirp.ID})
+		}
+		if len(published) < publishBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// How often purger removes expired chirps from trash
+const purgeInterval = time.Hour
+
+/**
+ * Remove chirps deleted more than 30 days ago. Chirp with replies becomes a tombstone
+ * without body, so threads are kept; other chirps are deleted with their media.
+ * Uploads which were never attached and aren't avatars are removed after a day.
+ * Expired tokens for password reset and email verification are deleted too, MFA challenges
+ * when they don't count for the limit of failed attempts anymore
+ */
+func (cfg *apiConfig) runPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := cfg.purgeExpiredChirps(ctx)
+		if err != nil {
+			log.Printf("purge deleted chirps: %v", err)
+		}
+
+		removed, err := cfg.db.DeleteUnattachedMedia(ctx, time.Now().UTC().Add(-unattachedMediaRetention))
+		if err != nil {
+			log.Printf("purge unattached media: %v", err)
+		}
+		cfg.deleteMediaFiles(ctx, removed)
+
+		_, err = cfg.db.DeleteExpiredAccountTokens(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("purge expired account tokens: %v", err)
+		}
+
+		_, err = cfg.db.DeleteExpiredMFAChallenges(ctx, time.Now().UTC().Add(-mfaFailureWindow))
+		if err != nil {
+			log.Printf("purge expired MFA challenges: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) purgeExpiredChirps(ctx context.Context) error {
+	expired := sql.NullTime{Time: time.Now().UTC().Add(-trashRetention), Valid: true}
+
+	removed, err := cfg.db.DeleteExpiredMedia(ctx, expired)
+	if err != nil {
+		return err
+	}
+	cfg.deleteMediaFiles(ctx, removed)
+
+	_, err = cfg.db.TombstoneExpiredChirps(ctx, expired)
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.db.PurgeExpiredChirps(ctx, expired)
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/St5/goboot-srv/internal/auth"
+	"github.com/google/uuid"
+)
+
+var testID = uuid.NewString()
+
+/**
+ * Routes which need access token of the user
+ */
+var tokenRoutes = []struct {
+	pattern string
+	target  string
+	handler func(*apiConfig, http.ResponseWriter, *http.Request)
+}{
+	{"PUT /api/chirps/{chirpID}", "/api/chirps/" + testID, (*apiConfig).handleUpdateChirp},
+	{"GET /api/drafts", "/api/drafts", (*apiConfig).handleGetDrafts},
+	{"POST /api/drafts/{chirpID}/publish", "/api/drafts/" + testID + "/publish", (*apiConfig).handlePublishDraft},
+	{"GET /api/trash", "/api/trash", (*apiConfig).handleGetTrash},
+	{"POST /api/chirps/{chirpID}/restore", "/api/chirps/" + testID + "/restore", (*apiConfig).handleRestoreChirp},
+	{"POST /api/chirps/{chirpID}/poll/votes", "/api/chirps/" + testID + "/poll/votes", (*apiConfig).handleCreatePollVote},
+	{"POST /api/chirps/{chirpID}/bookmark", "/api/chirps/" + testID + "/bookmark", (*apiConfig).handleCreateBookmark},
+	{"DELETE /api/chirps/{chirpID}/bookmark", "/api/chirps/" + testID + "/bookmark", (*apiConfig).handleDeleteBookmark},
+	{"GET /api/bookmarks", "/api/bookmarks", (*apiConfig).handleGetBookmarks},
+	{"POST /api/chirps/{chirpID}/pin", "/api/chirps/" + testID + "/pin", (*apiConfig).handlePinChirp},
+	{"DELETE /api/chirps/{chirpID}/pin", "/api/chirps/" + testID + "/pin", (*apiConfig).handleUnpinChirp},
+	{"POST /api/users/{userID}/block", "/api/users/" + testID + "/block", (*apiConfig).handleBlockUser},
+	{"DELETE /api/users/{userID}/block", "/api/users/" + testID + "/block", (*apiConfig).handleUnblockUser},
+	{"POST /api/users/{userID}/mute", "/api/users/" + testID + "/mute", (*apiConfig).handleMuteUser},
+	{"DELETE /api/users/{userID}/mute", "/api/users/" + testID + "/mute", (*apiConfig).handleUnmuteUser},
+	{"POST /api/conversations", "/api/conversations", (*apiConfig).handleCreateConversation},
+	{"GET /api/conversations", "/api/conversations", (*apiConfig).handleGetConversations},
+	{"GET /api/conversations/{conversationID}", "/api/conversations/" + testID, (*apiConfig).handleGetConversation},
+	{"POST /api/conversations/{conversationID}/messages", "/api/conversations/" + testID + "/messages", (*apiConfig).handleCreateMessage},
+	{"GET /api/conversations/{conversationID}/messages", "/api/conversations/" + testID + "/messages", (*apiConfig).handleGetMessages},
+	{"POST /api/conversations/{conversationID}/read", "/api/conversations/" + testID + "/read", (*apiConfig).handleMarkConversationRead},
+	{"GET /api/notifications", "/api/notifications", (*apiConfig).handleGetNotifications},
+	{"GET /api/notifications/unread", "/api/notifications/unread", (*apiConfig).handleGetUnreadNotifications},
+	{"POST /api/notifications/read", "/api/notifications/read", (*apiConfig).handleMarkAllNotificationsRead},
+	{"POST /api/notifications/{notificationID}/read", "/api/notifications/" + testID + "/read", (*apiConfig).handleMarkNotificationRead},
+	{"GET /api/notifications/preferences", "/api/notifications/preferences", (*apiConfig).handleGetNotificationPreferences},
+	{"PUT /api/notifications/preferences", "/api/notifications/preferences", (*apiConfig).handleUpdateNotificationPreferences},
+	{"POST /api/email/verification", "/api/email/verification", (*apiConfig).handleRequestEmailVerification},
+	{"POST /api/users/2fa/setup", "/api/users/2fa/setup", (*apiConfig).handleSetupTwoFactor},
+	{"POST /api/users/2fa/verify", "/api/users/2fa/verify", (*apiConfig).handleVerifyTwoFactor},
+	{"DELETE /api/users/2fa", "/api/users/2fa", (*apiConfig).handleDisableTwoFactor},
+	{"POST /api/federation/following", "/api/federation/following", (*apiConfig).handleFollowRemote},
+	{"GET /api/federation/following", "/api/federation/following", (*apiConfig).handleGetRemoteFollowing},
+	{"DELETE /api/federation/following/{actorID}", "/api/federation/following/" + testID, (*apiConfig).handleUnfollowRemote},
+}
+
+func TestRoutesRequireToken(t *testing.T) {
+	userID := uuid.New()
+	expired, _ := auth.MakeJWT(userID, testSecret, -time.Minute)
+	otherSecret, _ := auth.MakeJWT(userID, "other-secret", time.Minute)
+
+	tokens := []struct {
+		name  string
+		token string
+	}{
+		{name: "no token", token: ""},
+		{name: "malformed token", token: "nope"},
+		{name: "expired token", token: expired},
+		{name: "token of other server", token: otherSecret},
+	}
+
+	for _, route := range tokenRoutes {
+		for _, tt := range tokens {
+			t.Run(route.pattern+" "+tt.name, func(t *testing.T) {
+				db := newFakeDB()
+				cfg := newTestConfig(t, db)
+				handler := func(w http.ResponseWriter, r *http.Request) { route.handler(cfg, w, r) }
+
+				rec := serveTest(route.pattern, handler, route.target, tt.token, "{}")
+				if rec.Code != http.StatusUnauthorized {
+					t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,15 @@
+-- name: CreateAccountToken :exec
+INSERT INTO account_tokens (token_hash, user_id, purpose, email, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, now());
+
+-- name: InvalidateAccountTokens :exec
+UPDATE account_tokens SET used_at = now()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: ConsumeAccountToken :one
+UPDATE account_tokens SET used_at = now()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredAccountTokens :execrows
+DELETE FROM account_tokens WHERE expires_at < $1;
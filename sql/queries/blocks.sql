@@ -0,0 +1,30 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+SELECT is_blocked(sqlc.arg(author_id)::uuid, sqlc.arg(user_id)::uuid);
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+   OR (follower_id = $2 AND followee_id = $1);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetHiddenUserIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = $1
+UNION
+SELECT blocker_id FROM blocks WHERE blocked_id = $1
+UNION
+SELECT muted_id FROM mutes WHERE muter_id = $1;
@@ -0,0 +1,23 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+SELECT sqlc.embed(c), b.created_at AS bookmarked_at FROM bookmarks AS b
+JOIN chirps AS c ON c.id = b.chirp_id
+WHERE b.user_id = sqlc.arg(user_id)
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND NOT is_hidden(sqlc.arg(user_id), c.user_id)
+  AND can_read(sqlc.arg(user_id), c.user_id, c.id, c.visibility)
+  AND (b.created_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY b.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetViewerBookmarks :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
@@ -1,22 +1,246 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, user_id, body)
-VALUES (gen_random_uuid(), now(), now(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, user_id, body, in_reply_to, quote_of, rechirp_of, status, publish_at, visibility)
+VALUES (gen_random_uuid(), now(), now(), $1, $2, $3, $4, $5, $6, $7, $8)
 Returning *;
 
 -- name: ResetAllChirps :exec
 DELETE FROM chirps;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at;
+SELECT * FROM chirps WHERE deleted_at IS NULL AND status = 'published' ORDER BY created_at;
 
 -- name: GetAllChirpsDesc :many
-SELECT * FROM chirps ORDER BY created_at DESC;
+SELECT * FROM chirps WHERE deleted_at IS NULL AND status = 'published' ORDER BY created_at DESC;
 
 -- name: GetChirpByID :one
 SELECT * FROM chirps WHERE id = $1;
 
+-- name: GetVisibleChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+  AND NOT is_blocked(user_id, sqlc.arg(viewer_id)::uuid)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility);
+
+-- name: GetVisibleChirpByID :one
+SELECT * FROM chirps
+WHERE id = sqlc.arg(id)
+  AND NOT is_blocked(user_id, sqlc.arg(viewer_id)::uuid)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility);
+
 -- name: DeleteChirpByID :exec
 DELETE FROM chirps WHERE id = $1;
 
 -- name: GetChirpsByUserID :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY $2;
\ No newline at end of file
+SELECT * FROM chirps WHERE user_id = $1 AND deleted_at IS NULL AND status = 'published' ORDER BY $2;
+
+-- name: UpdateChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), c.id, c.body, c.updated_at, now() FROM chirps AS c WHERE c.id = $2
+)
+UPDATE chirps SET body = $1, updated_at = now()
+WHERE id = $2
+RETURNING *;
+
+-- name: GetChirpsPage :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND status = 'published'
+  AND visibility <> 'unlisted'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpsPageDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND status = 'published'
+  AND visibility <> 'unlisted'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpsPageByUserID :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND status = 'published'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpsPageByUserIDDesc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND status = 'published'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(c), ts_rank(c.search_vector, q.query) AS rank,
+    ts_headline('english', c.body, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+FROM chirps AS c, websearch_to_tsquery('english', sqlc.arg(query)::text) AS q(query)
+WHERE c.search_vector @@ q.query
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND c.visibility <> 'unlisted'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, c.user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, c.user_id, c.id, c.visibility)
+  AND (sqlc.narg(author_id)::uuid IS NULL OR c.user_id = sqlc.narg(author_id)::uuid)
+  AND (sqlc.narg(created_since)::timestamp IS NULL OR c.created_at >= sqlc.narg(created_since)::timestamp)
+  AND (sqlc.narg(created_until)::timestamp IS NULL OR c.created_at < sqlc.narg(created_until)::timestamp)
+  AND (ts_rank(c.search_vector, q.query), c.created_at, c.id)
+    < (sqlc.arg(cursor_rank)::real, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY rank DESC, c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetTimeline :many
+SELECT c.* FROM chirps AS c
+JOIN follows AS f ON f.followee_id = c.user_id
+WHERE f.follower_id = sqlc.arg(user_id)
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND NOT is_hidden(sqlc.arg(user_id), c.user_id)
+  AND can_read(sqlc.arg(user_id), c.user_id, c.id, c.visibility)
+  AND (c.created_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: SoftDeleteChirpByID :exec
+UPDATE chirps SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreChirpByID :one
+UPDATE chirps SET deleted_at = NULL
+WHERE id = sqlc.arg(id) AND tombstoned_at IS NULL AND deleted_at >= sqlc.arg(deleted_since)::timestamp
+RETURNING *;
+
+-- name: GetLastChirpDeletion :one
+SELECT max(c.deleted_at)::timestamp AS deleted_at FROM chirps AS c
+WHERE c.deleted_at IS NOT NULL
+  AND c.status = 'published'
+  AND (sqlc.narg(user_id)::uuid IS NULL OR c.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+      SELECT 1 FROM chirp_tags AS t WHERE t.chirp_id = c.id AND t.tag = lower(sqlc.narg(tag)::text)
+  ));
+
+-- name: GetDeletedChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND tombstoned_at IS NULL
+  AND deleted_at >= sqlc.arg(deleted_since)::timestamp
+  AND (deleted_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY deleted_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetDeletedChirps :many
+SELECT * FROM chirps
+WHERE deleted_at IS NOT NULL
+  AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id)::uuid)
+  AND (deleted_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY deleted_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: TombstoneExpiredChirps :execrows
+WITH revisions AS (
+    DELETE FROM chirp_revisions WHERE chirp_id IN (
+        SELECT id FROM chirps WHERE deleted_at < $1 AND tombstoned_at IS NULL
+    )
+)
+UPDATE chirps SET body = '', tombstoned_at = now()
+WHERE deleted_at < $1 AND tombstoned_at IS NULL;
+
+-- name: PurgeExpiredChirps :execrows
+DELETE FROM chirps AS c
+WHERE c.deleted_at < $1
+  AND NOT EXISTS (SELECT 1 FROM chirps AS reply WHERE reply.in_reply_to = c.id);
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.*, 1 AS depth FROM chirps AS parent
+    WHERE parent.id = (SELECT c.in_reply_to FROM chirps AS c WHERE c.id = sqlc.arg(id))
+    UNION ALL
+    SELECT parent.*, a.depth + 1 FROM chirps AS parent
+    JOIN ancestors AS a ON parent.id = a.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+FROM ancestors
+WHERE NOT is_blocked(user_id, sqlc.arg(viewer_id)::uuid)
+  AND can_read(sqlc.arg(viewer_id)::uuid, user_id, id, visibility)
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT reply.* FROM chirps AS reply
+    WHERE reply.in_reply_to = sqlc.arg(id) AND reply.status = 'published'
+    UNION ALL
+    SELECT reply.* FROM chirps AS reply
+    JOIN descendants AS d ON reply.in_reply_to = d.id
+    WHERE reply.status = 'published'
+)
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, reply_count, tombstoned_at, quote_of, rechirp_of, status, publish_at, deleted_at, visibility
+FROM descendants AS d
+WHERE (d.deleted_at IS NULL OR EXISTS (SELECT 1 FROM chirps AS reply WHERE reply.in_reply_to = d.id))
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, d.user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, d.user_id, d.id, d.visibility)
+ORDER BY created_at, id
+LIMIT sqlc.arg(max_rows);
+
+-- name: GetUnpublishedChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND status <> 'published'
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ScheduleChirp :one
+UPDATE chirps SET status = 'scheduled', publish_at = $2, updated_at = now()
+WHERE id = $1 AND status <> 'published'
+RETURNING *;
+
+-- name: PublishChirp :one
+UPDATE chirps SET status = 'published', publish_at = NULL, created_at = now(), updated_at = now()
+WHERE id = $1 AND status <> 'published'
+RETURNING *;
+
+-- name: PublishDueChirps :many
+UPDATE chirps SET status = 'published', publish_at = NULL, created_at = now(), updated_at = now()
+WHERE status = 'scheduled' AND id IN (
+    SELECT id FROM chirps
+    WHERE status = 'scheduled' AND publish_at <= now() AND deleted_at IS NULL
+    ORDER BY publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UnscheduleChirpsWithHiddenTargets :many
+UPDATE chirps AS c SET status = 'draft', publish_at = NULL, updated_at = now()
+WHERE c.status = 'scheduled' AND c.publish_at <= now() AND c.deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM chirps AS t
+      WHERE t.id IN (c.in_reply_to, c.quote_of, c.rechirp_of)
+        AND (t.status <> 'published'
+          OR t.deleted_at IS NOT NULL
+          OR is_blocked(t.user_id, c.user_id)
+          OR NOT can_read(c.user_id, t.user_id, t.id, t.visibility)
+          OR (t.id IN (c.quote_of, c.rechirp_of) AND t.visibility NOT IN ('public', 'unlisted')))
+  )
+RETURNING *;
+
+-- name: UnscheduleChirpsWithClosedPolls :many
+UPDATE chirps SET status = 'draft', publish_at = NULL, updated_at = now()
+WHERE status = 'scheduled' AND publish_at <= now() AND deleted_at IS NULL
+  AND EXISTS (SELECT 1 FROM polls WHERE polls.chirp_id = chirps.id AND polls.closes_at <= now())
+RETURNING *;
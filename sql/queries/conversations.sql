@@ -0,0 +1,79 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_by, direct_key, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, now(), now())
+RETURNING *;
+
+-- name: AddConversationParticipants :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+SELECT sqlc.arg(conversation_id)::uuid, unnest(sqlc.arg(user_ids)::uuid[]), now()
+ON CONFLICT DO NOTHING;
+
+-- name: GetConversationByDirectKey :one
+SELECT * FROM conversations WHERE direct_key = $1;
+
+-- name: GetConversationForUser :one
+SELECT c.* FROM conversations AS c
+JOIN conversation_participants AS p ON p.conversation_id = c.id
+WHERE c.id = $1 AND p.user_id = $2;
+
+-- name: GetConversationsByUserID :many
+SELECT c.* FROM conversations AS c
+JOIN conversation_participants AS p ON p.conversation_id = c.id
+WHERE p.user_id = sqlc.arg(user_id)
+  AND (c.updated_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.updated_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetConversationParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY joined_at, user_id;
+
+-- name: GetUnreadCounts :many
+SELECT m.conversation_id, count(*) AS unread FROM messages AS m
+JOIN conversation_participants AS p ON p.conversation_id = m.conversation_id AND p.user_id = sqlc.arg(user_id)
+WHERE m.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+  AND m.sender_id <> sqlc.arg(user_id)
+  AND (p.last_read_at IS NULL OR m.created_at > p.last_read_at)
+GROUP BY m.conversation_id;
+
+-- name: CountUsersByIDs :one
+SELECT count(*) FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: HasBlockBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM unnest(sqlc.arg(user_ids)::uuid[]) AS u(id)
+    WHERE is_blocked(u.id, sqlc.arg(user_id)::uuid) OR is_blocked(sqlc.arg(user_id)::uuid, u.id)
+);
+
+-- name: HasBlockInConversation :one
+SELECT EXISTS (
+    SELECT 1 FROM conversation_participants AS p
+    WHERE p.conversation_id = sqlc.arg(conversation_id)
+      AND p.user_id <> sqlc.arg(user_id)
+      AND (is_blocked(p.user_id, sqlc.arg(user_id)::uuid) OR is_blocked(sqlc.arg(user_id)::uuid, p.user_id))
+);
+
+-- name: CreateMessage :one
+WITH touched AS (
+    UPDATE conversations SET updated_at = now() WHERE id = sqlc.arg(conversation_id)
+)
+INSERT INTO messages (id, conversation_id, sender_id, body, created_at)
+VALUES (gen_random_uuid(), sqlc.arg(conversation_id), sqlc.arg(sender_id), sqlc.arg(body), now())
+RETURNING *;
+
+-- name: GetMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetMessageByID :one
+SELECT * FROM messages WHERE id = $1 AND conversation_id = $2;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = sqlc.arg(read_at)
+WHERE conversation_id = sqlc.arg(conversation_id)
+  AND user_id = sqlc.arg(user_id)
+  AND (last_read_at IS NULL OR last_read_at < sqlc.arg(read_at));
@@ -0,0 +1,92 @@
+-- name: GetActorKey :one
+SELECT * FROM actor_keys WHERE user_id = $1;
+
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys (user_id, public_key_pem, private_key_pem, created_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT DO NOTHING;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, uri, key_id, inbox, preferred_username, public_key_pem, fetched_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, now())
+ON CONFLICT (uri) DO UPDATE
+SET key_id = excluded.key_id, inbox = excluded.inbox, preferred_username = excluded.preferred_username,
+    public_key_pem = excluded.public_key_pem, fetched_at = excluded.fetched_at
+RETURNING *;
+
+-- name: GetRemoteActorByKeyID :one
+SELECT * FROM remote_actors WHERE key_id = $1 LIMIT 1;
+
+-- name: GetRemoteActorByID :one
+SELECT * FROM remote_actors WHERE id = $1;
+
+-- name: GetRemoteActorByURI :one
+SELECT * FROM remote_actors WHERE uri = $1;
+
+-- name: DeleteRemoteActor :exec
+DELETE FROM remote_actors WHERE id = $1;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO remote_followers (user_id, actor_id, follow_uri, created_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (user_id, actor_id) DO UPDATE SET follow_uri = excluded.follow_uri;
+
+-- name: DeleteRemoteFollower :exec
+DELETE FROM remote_followers WHERE user_id = $1 AND actor_id = $2;
+
+-- name: GetRemoteFollowerInboxes :many
+SELECT DISTINCT a.inbox FROM remote_followers AS f
+JOIN remote_actors AS a ON a.id = f.actor_id
+WHERE f.user_id = $1;
+
+-- name: CountAllFollowers :one
+SELECT (SELECT count(*) FROM follows WHERE followee_id = sqlc.arg(user_id))
+     + (SELECT count(*) FROM remote_followers WHERE user_id = sqlc.arg(user_id)) AS total;
+
+-- name: CreateRemoteFollowing :exec
+INSERT INTO remote_following (user_id, actor_id, follow_uri, created_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (user_id, actor_id) DO UPDATE SET follow_uri = excluded.follow_uri, accepted = false;
+
+-- name: AcceptRemoteFollowing :execrows
+UPDATE remote_following SET accepted = true
+WHERE follow_uri = $1 AND actor_id = $2;
+
+-- name: DeleteRemoteFollowing :one
+DELETE FROM remote_following WHERE user_id = $1 AND actor_id = $2
+RETURNING *;
+
+-- name: GetRemoteFollowing :many
+SELECT sqlc.embed(a), f.accepted, f.created_at AS followed_at FROM remote_following AS f
+JOIN remote_actors AS a ON a.id = f.actor_id
+WHERE f.user_id = $1
+ORDER BY f.created_at DESC;
+
+-- name: IsFollowingRemoteActor :one
+SELECT EXISTS (
+    SELECT 1 FROM remote_following WHERE user_id = $1 AND actor_id = $2
+) AS following;
+
+-- name: CreateRemoteNote :exec
+INSERT INTO remote_notes (id, uri, actor_id, content, url, in_reply_to, published_at, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, now())
+ON CONFLICT (uri) DO NOTHING;
+
+-- name: DeleteRemoteNote :exec
+DELETE FROM remote_notes WHERE uri = $1 AND actor_id = $2;
+
+-- name: GetRemoteTimeline :many
+SELECT sqlc.embed(n), a.uri AS actor_uri, a.preferred_username FROM remote_notes AS n
+JOIN remote_actors AS a ON a.id = n.actor_id
+JOIN remote_following AS f ON f.actor_id = n.actor_id AND f.user_id = sqlc.arg(user_id)
+WHERE (n.published_at, n.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY n.published_at DESC, n.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: CreateRemoteLike :exec
+INSERT INTO remote_likes (chirp_id, actor_id, like_uri, created_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteRemoteLike :exec
+DELETE FROM remote_likes WHERE like_uri = $1 AND actor_id = $2;
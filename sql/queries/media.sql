@@ -0,0 +1,31 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, user_id, content_type, size, width, height, blob_key, thumbnail_key, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, now())
+RETURNING *;
+
+-- name: GetMediaByID :one
+SELECT * FROM media WHERE id = $1;
+
+-- name: AttachMedia :execrows
+UPDATE media SET chirp_id = sqlc.arg(chirp_id), position = array_position(sqlc.arg(ids)::uuid[], id)
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND user_id = sqlc.arg(user_id) AND chirp_id IS NULL AND NOT is_avatar;
+
+-- name: GetMediaByChirpIDs :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: DeleteExpiredMedia :many
+DELETE FROM media
+WHERE chirp_id IN (SELECT id FROM chirps WHERE deleted_at < $1)
+RETURNING *;
+
+-- name: MarkMediaAvatar :execrows
+UPDATE media SET is_avatar = true WHERE id = $1 AND user_id = $2 AND chirp_id IS NULL;
+
+-- name: DeleteUnattachedMedia :many
+DELETE FROM media AS m
+WHERE m.chirp_id IS NULL
+  AND m.created_at < $1
+  AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_id = m.id)
+RETURNING *;
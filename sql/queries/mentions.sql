@@ -0,0 +1,26 @@
+-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT sqlc.arg(chirp_id)::uuid, u.id FROM users AS u
+WHERE lower(u.handle) = ANY(sqlc.arg(handles)::text[])
+  AND NOT is_blocked(u.id, (SELECT c.user_id FROM chirps AS c WHERE c.id = sqlc.arg(chirp_id)::uuid))
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
+
+-- name: GetMentionsByChirpIDs :many
+SELECT m.chirp_id, m.user_id, u.handle FROM chirp_mentions AS m
+JOIN users AS u ON u.id = m.user_id
+WHERE m.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpsMentioningUser :many
+SELECT c.* FROM chirp_mentions AS m
+JOIN chirps AS c ON c.id = m.chirp_id
+WHERE m.user_id = sqlc.arg(user_id)
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, c.user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, c.user_id, c.id, c.visibility)
+  AND (c.created_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,26 @@
+-- name: GetModerationWords :many
+SELECT * FROM moderation_words ORDER BY word;
+
+-- name: CreateModerationWord :one
+INSERT INTO moderation_words (word, action, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT (word) DO UPDATE SET action = EXCLUDED.action
+RETURNING *;
+
+-- name: DeleteModerationWord :execrows
+DELETE FROM moderation_words WHERE word = $1;
+
+-- name: FlagChirp :exec
+INSERT INTO chirp_flags (chirp_id, reasons, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT (chirp_id) DO UPDATE SET reasons = EXCLUDED.reasons, created_at = now();
+
+-- name: DeleteChirpFlag :execrows
+DELETE FROM chirp_flags WHERE chirp_id = $1;
+
+-- name: GetFlaggedChirps :many
+SELECT sqlc.embed(c), f.reasons, f.created_at AS flagged_at FROM chirp_flags AS f
+JOIN chirps AS c ON c.id = f.chirp_id
+WHERE (f.created_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY f.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
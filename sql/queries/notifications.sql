@@ -0,0 +1,46 @@
+-- name: CreateNotifications :many
+INSERT INTO notifications (id, user_id, type, actor_id, chirp_id, created_at)
+SELECT gen_random_uuid(), u.id, sqlc.arg(type), sqlc.arg(actor_id), sqlc.narg(chirp_id), now()
+FROM unnest(sqlc.arg(user_ids)::uuid[]) AS u(id)
+WHERE u.id <> sqlc.arg(actor_id)
+  AND NOT is_hidden(u.id, sqlc.arg(actor_id))
+  AND NOT EXISTS (
+      SELECT 1 FROM notification_preferences AS p
+      WHERE p.user_id = u.id AND p.type = sqlc.arg(type) AND NOT p.enabled
+  )
+  AND (sqlc.narg(chirp_id)::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM chirps AS c
+      WHERE c.id = sqlc.narg(chirp_id)::uuid AND can_read(u.id, c.user_id, c.id, c.visibility)
+  ))
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+  AND (NOT sqlc.arg(unread_only)::boolean OR read_at IS NULL)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetUnreadNotificationCounts :many
+SELECT type, count(*) AS unread FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+GROUP BY type;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences WHERE user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled;
+
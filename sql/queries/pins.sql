@@ -0,0 +1,32 @@
+-- name: LockUserByID :exec
+SELECT id FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: CountPinnedChirps :one
+SELECT count(*) FROM pinned_chirps AS p
+JOIN chirps AS c ON c.id = p.chirp_id
+WHERE p.user_id = $1 AND c.deleted_at IS NULL;
+
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinChirp :exec
+DELETE FROM pinned_chirps WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetPinnedChirpsByUserID :many
+SELECT c.* FROM pinned_chirps AS p
+JOIN chirps AS c ON c.id = p.chirp_id
+WHERE p.user_id = sqlc.arg(user_id)
+  AND c.user_id = sqlc.arg(user_id)
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, c.user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, c.user_id, c.id, c.visibility)
+ORDER BY p.created_at DESC
+LIMIT sqlc.arg(pin_limit);
+
+-- name: GetPinnedChirpIDs :many
+SELECT p.chirp_id FROM pinned_chirps AS p
+JOIN chirps AS c ON c.id = p.chirp_id
+WHERE p.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]) AND p.user_id = c.user_id;
@@ -0,0 +1,31 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, closes_at, created_at)
+VALUES ($1, $2, now());
+
+-- name: CreatePollOptions :exec
+INSERT INTO poll_options (chirp_id, position, text)
+SELECT sqlc.arg(chirp_id)::uuid, o.position - 1, o.text
+FROM unnest(sqlc.arg(options)::text[]) WITH ORDINALITY AS o(text, position);
+
+-- name: GetPollByChirpID :one
+SELECT * FROM polls WHERE chirp_id = $1;
+
+-- name: GetPollsByChirpIDs :many
+SELECT * FROM polls WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollTallies :many
+SELECT o.chirp_id, o.position, o.text, count(v.user_id) AS votes
+FROM poll_options AS o
+LEFT JOIN poll_votes AS v ON v.chirp_id = o.chirp_id AND v.position = o.position
+WHERE o.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY o.chirp_id, o.position, o.text
+ORDER BY o.chirp_id, o.position;
+
+-- name: GetViewerVotes :many
+SELECT chirp_id, position FROM poll_votes
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT DO NOTHING;
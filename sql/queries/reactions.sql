@@ -0,0 +1,32 @@
+-- name: CreateReaction :exec
+INSERT INTO chirp_reactions (chirp_id, user_id, reaction, created_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteReaction :exec
+DELETE FROM chirp_reactions WHERE chirp_id = $1 AND user_id = $2 AND reaction = $3;
+
+-- name: GetReactionCounts :many
+SELECT chirp_id, reaction, count(*) AS count FROM (
+    SELECT chirp_id, reaction FROM chirp_reactions WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+    UNION ALL
+    SELECT chirp_id, 'like' FROM remote_likes WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+) AS r
+GROUP BY chirp_id, reaction;
+
+-- name: GetViewerReactions :many
+SELECT chirp_id, reaction FROM chirp_reactions
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetReactedChirpsByUserID :many
+SELECT sqlc.embed(c), r.created_at AS reacted_at FROM chirp_reactions AS r
+JOIN chirps AS c ON c.id = r.chirp_id
+WHERE r.user_id = sqlc.arg(user_id)
+  AND r.reaction = sqlc.arg(reaction)
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, c.user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, c.user_id, c.id, c.visibility)
+  AND (r.created_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY r.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,20 @@
+-- name: CreateChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag)
+SELECT sqlc.arg(chirp_id)::uuid, unnest(sqlc.arg(tags)::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags WHERE chirp_id = $1;
+
+-- name: GetChirpsByTag :many
+SELECT c.* FROM chirp_tags AS t
+JOIN chirps AS c ON c.id = t.chirp_id
+WHERE t.tag = lower(sqlc.arg(tag))
+  AND c.deleted_at IS NULL
+  AND c.status = 'published'
+  AND c.visibility <> 'unlisted'
+  AND NOT is_hidden(sqlc.arg(viewer_id)::uuid, c.user_id)
+  AND can_read(sqlc.arg(viewer_id)::uuid, c.user_id, c.id, c.visibility)
+  AND (c.created_at, c.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
@@ -11,3 +11,7 @@ WHERE token = $1 AND expires_at > now() AND revoked_at IS NULL;
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens SET revoked_at = now()
 WHERE token = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
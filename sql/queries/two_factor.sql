@@ -0,0 +1,59 @@
+-- name: CreateTOTPSecret :execrows
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT (user_id) DO UPDATE
+SET secret = excluded.secret, last_counter = 0, created_at = excluded.created_at
+WHERE user_totp.enabled_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- name: IsTOTPEnabled :one
+SELECT EXISTS (
+    SELECT 1 FROM user_totp WHERE user_id = $1 AND enabled_at IS NOT NULL
+) AS enabled;
+
+-- name: EnableTOTP :execrows
+UPDATE user_totp SET enabled_at = now(), last_counter = sqlc.arg(counter)
+WHERE user_id = sqlc.arg(user_id) AND enabled_at IS NULL AND last_counter < sqlc.arg(counter);
+
+-- name: UseTOTPCounter :execrows
+UPDATE user_totp SET last_counter = sqlc.arg(counter)
+WHERE user_id = sqlc.arg(user_id) AND enabled_at IS NOT NULL AND last_counter < sqlc.arg(counter);
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (code_hash, user_id, created_at)
+SELECT unnest(sqlc.arg(code_hashes)::text[]), sqlc.arg(user_id)::uuid, now();
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (id, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, now());
+
+-- name: ClaimMFAChallenge :execrows
+UPDATE mfa_challenges SET attempts = attempts + 1
+WHERE id = sqlc.arg(id)
+  AND user_id = sqlc.arg(user_id)
+  AND used_at IS NULL
+  AND expires_at > now()
+  AND attempts < sqlc.arg(max_attempts);
+
+-- name: CompleteMFAChallenge :execrows
+UPDATE mfa_challenges SET used_at = now() WHERE id = $1 AND used_at IS NULL;
+
+-- name: CountMFAFailures :one
+SELECT (COALESCE(sum(attempts), 0) - count(used_at))::int AS failures
+FROM mfa_challenges
+WHERE user_id = sqlc.arg(user_id) AND created_at > sqlc.arg(since)::timestamp;
+
+-- name: DeleteExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges WHERE expires_at < $1;
@@ -1,17 +1,20 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), now(), now(), $1, $2)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (gen_random_uuid(), now(), now(), $1, $2, $3)
 Returning *;
 
 -- name: ResetAllUsers :exec
 DELETE FROM users;
 
 -- name: GetUserByEmail :one
-SELECT * FROM users WHERE email = $1;
+SELECT * FROM users WHERE lower(email) = lower(sqlc.arg(email)::text);
 
 -- name: UpdateUser :one
-UPDATE users SET email = $1, hashed_password = $2, updated_at = now()
-WHERE id = $3
+UPDATE users
+SET email = sqlc.arg(email), hashed_password = sqlc.arg(hashed_password),
+    handle = coalesce(sqlc.narg(handle), handle), updated_at = now(),
+    email_verified_at = CASE WHEN lower(email) = lower(sqlc.arg(email)::text) THEN email_verified_at END
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 -- name: UpdateChirpyRedByUserID :exec
@@ -19,4 +22,44 @@ UPDATE users SET is_chirpy_red = $1, updated_at = now()
 WHERE id = $2;
 
 -- name: GetUserByID :one
-SELECT * FROM users WHERE id = $1;
\ No newline at end of file
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUserHandlesByIDs :many
+SELECT id, handle FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE lower(handle) = lower(sqlc.arg(handle)::text);
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = coalesce(sqlc.narg(handle), handle), display_name = sqlc.arg(display_name), bio = sqlc.arg(bio),
+    avatar_id = sqlc.narg(avatar_id), updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetUserProfile :one
+SELECT u.id, u.handle, u.display_name, u.bio, m.blob_key AS avatar_key, u.created_at,
+    (SELECT count(*) FROM follows WHERE followee_id = u.id)
+        + (SELECT count(*) FROM remote_followers WHERE user_id = u.id) AS followers_count,
+    (SELECT count(*) FROM follows WHERE follower_id = u.id)
+        + (SELECT count(*) FROM remote_following WHERE user_id = u.id AND accepted) AS following_count,
+    (SELECT count(*) FROM chirps AS c
+     WHERE c.user_id = u.id AND c.deleted_at IS NULL AND c.status = 'published'
+       AND can_read(sqlc.arg(viewer_id)::uuid, c.user_id, c.id, c.visibility)) AS chirps_count
+FROM users AS u
+LEFT JOIN media AS m ON m.id = u.avatar_id
+WHERE lower(u.handle) = lower(sqlc.arg(handle)::text)
+  AND NOT is_blocked(u.id, sqlc.arg(viewer_id)::uuid);
+
+-- name: GetAuthorsByIDs :many
+SELECT u.id, u.handle, u.display_name, m.blob_key AS avatar_key FROM users AS u
+LEFT JOIN media AS m ON m.id = u.avatar_id
+WHERE u.id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = $1, updated_at = now()
+WHERE id = $2;
+
+-- name: MarkEmailVerified :execrows
+UPDATE users SET email_verified_at = now(), updated_at = now()
+WHERE id = sqlc.arg(id) AND lower(email) = lower(sqlc.arg(email)::text);
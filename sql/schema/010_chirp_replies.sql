@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID NULL REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN tombstoned_at TIMESTAMP NULL;
+
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to) WHERE in_reply_to IS NOT NULL;
+
+-- +goose StatementBegin
+CREATE FUNCTION chirps_update_reply_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.in_reply_to IS NOT NULL THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    ELSIF TG_OP = 'DELETE' AND OLD.in_reply_to IS NOT NULL THEN
+        UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_update_reply_count();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_reply_count ON chirps;
+DROP FUNCTION IF EXISTS chirps_update_reply_count();
+DROP INDEX IF EXISTS chirps_in_reply_to_idx;
+ALTER TABLE chirps
+DROP COLUMN tombstoned_at,
+DROP COLUMN reply_count,
+DROP COLUMN in_reply_to;
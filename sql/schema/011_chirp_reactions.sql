@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE chirp_reactions (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    reaction VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chirp_id, user_id, reaction),
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_reactions_user_id_idx ON chirp_reactions (user_id, reaction, created_at, chirp_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_reactions;
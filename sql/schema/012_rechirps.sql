@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN quote_of UUID NULL,
+ADD COLUMN rechirp_of UUID NULL,
+ADD CONSTRAINT chirps_quote_or_rechirp CHECK (quote_of IS NULL OR rechirp_of IS NULL);
+
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of) WHERE quote_of IS NOT NULL;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_user_id_rechirp_of_idx;
+DROP INDEX IF EXISTS chirps_quote_of_idx;
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_quote_or_rechirp,
+DROP COLUMN rechirp_of,
+DROP COLUMN quote_of;
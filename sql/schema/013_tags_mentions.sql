@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle VARCHAR(30) NULL;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (lower(handle));
+
+CREATE TABLE chirp_tags (
+    chirp_id UUID NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    PRIMARY KEY (chirp_id, tag),
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_tags_tag_idx ON chirp_tags (tag);
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_mentions;
+DROP TABLE IF EXISTS chirp_tags;
+DROP INDEX IF EXISTS users_handle_idx;
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE moderation_words (
+    word VARCHAR(100) PRIMARY KEY,
+    action VARCHAR(16) NOT NULL DEFAULT 'replace' CHECK (action IN ('replace', 'flag', 'reject')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO moderation_words (word, action) VALUES
+    ('kerfuffle', 'replace'),
+    ('sharbert', 'replace'),
+    ('fornax', 'replace');
+
+CREATE TABLE chirp_flags (
+    chirp_id UUID PRIMARY KEY,
+    reasons TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_flags_created_at_idx ON chirp_flags (created_at, chirp_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_flags;
+DROP TABLE IF EXISTS moderation_words;
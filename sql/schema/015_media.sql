@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE media (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    chirp_id UUID,
+    position INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    blob_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX media_chirp_id_idx ON media (chirp_id, position);
+
+-- +goose Down
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,58 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published',
+ADD COLUMN publish_at TIMESTAMP NULL,
+ADD CONSTRAINT chirps_status_check CHECK (status IN ('published', 'scheduled', 'draft')),
+ADD CONSTRAINT chirps_publish_at_check CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+CREATE INDEX chirps_scheduled_idx ON chirps (publish_at) WHERE status = 'scheduled';
+CREATE INDEX chirps_unpublished_user_id_idx ON chirps (user_id, created_at, id) WHERE status <> 'published';
+
+-- Replies count only when they are published
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirps_update_reply_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.in_reply_to IS NOT NULL AND NEW.status = 'published' THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    ELSIF TG_OP = 'UPDATE' AND NEW.in_reply_to IS NOT NULL AND OLD.status <> 'published' AND NEW.status = 'published' THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    ELSIF TG_OP = 'DELETE' AND OLD.in_reply_to IS NOT NULL AND OLD.status = 'published' THEN
+        UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS chirps_reply_count ON chirps;
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE OR UPDATE OF status ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_update_reply_count();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_reply_count ON chirps;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirps_update_reply_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.in_reply_to IS NOT NULL THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    ELSIF TG_OP = 'DELETE' AND OLD.in_reply_to IS NOT NULL THEN
+        UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_update_reply_count();
+
+DROP INDEX IF EXISTS chirps_unpublished_user_id_idx;
+DROP INDEX IF EXISTS chirps_scheduled_idx;
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_publish_at_check,
+DROP CONSTRAINT chirps_status_check,
+DROP COLUMN publish_at,
+DROP COLUMN status;
@@ -0,0 +1,81 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP NULL;
+
+-- Tombstones are deleted chirps which are kept for threads
+UPDATE chirps SET deleted_at = tombstoned_at WHERE tombstoned_at IS NOT NULL;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at, id) WHERE deleted_at IS NOT NULL;
+
+-- Chirps of a user are removed only explicitly, never together with the user
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_user_id_fkey,
+ADD CONSTRAINT chirps_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+-- Replies count only when they are published and not deleted
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirps_update_reply_count() RETURNS TRIGGER AS $$
+DECLARE
+    old_counted BOOLEAN := TG_OP <> 'INSERT' AND OLD.in_reply_to IS NOT NULL AND OLD.status = 'published' AND OLD.deleted_at IS NULL;
+    new_counted BOOLEAN := TG_OP <> 'DELETE' AND NEW.in_reply_to IS NOT NULL AND NEW.status = 'published' AND NEW.deleted_at IS NULL;
+BEGIN
+    IF old_counted AND NOT new_counted THEN
+        UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to;
+    ELSIF new_counted AND NOT old_counted THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS chirps_reply_count ON chirps;
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE OR UPDATE OF status, deleted_at ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_update_reply_count();
+
+UPDATE chirps SET reply_count = (
+    SELECT count(*) FROM chirps AS reply
+    WHERE reply.in_reply_to = chirps.id AND reply.status = 'published' AND reply.deleted_at IS NULL
+);
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_reply_count ON chirps;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirps_update_reply_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.in_reply_to IS NOT NULL AND NEW.status = 'published' THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    ELSIF TG_OP = 'UPDATE' AND NEW.in_reply_to IS NOT NULL AND OLD.status <> 'published' AND NEW.status = 'published' THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to;
+    ELSIF TG_OP = 'DELETE' AND OLD.in_reply_to IS NOT NULL AND OLD.status = 'published' THEN
+        UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE OR UPDATE OF status ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirps_update_reply_count();
+
+-- Soft deleted chirps become tombstones like chirps deleted before soft delete, without body and history.
+-- They can't be restored after rollback, but they and their replies are kept
+DELETE FROM chirp_revisions
+WHERE chirp_id IN (SELECT id FROM chirps WHERE deleted_at IS NOT NULL AND tombstoned_at IS NULL);
+
+UPDATE chirps SET body = '', tombstoned_at = deleted_at
+WHERE deleted_at IS NOT NULL AND tombstoned_at IS NULL;
+
+UPDATE chirps SET reply_count = (
+    SELECT count(*) FROM chirps AS reply
+    WHERE reply.in_reply_to = chirps.id AND reply.status = 'published'
+);
+
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_user_id_fkey,
+ADD CONSTRAINT chirps_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS chirps_deleted_at_idx;
+ALTER TABLE chirps DROP COLUMN deleted_at;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE polls (
+    chirp_id UUID PRIMARY KEY,
+    closes_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_options (
+    chirp_id UUID NOT NULL,
+    position INT NOT NULL,
+    text VARCHAR(100) NOT NULL,
+    PRIMARY KEY (chirp_id, position),
+    FOREIGN KEY (chirp_id) REFERENCES polls(chirp_id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_votes (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    position INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id, position) REFERENCES poll_options(chirp_id, position) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
@@ -0,0 +1,50 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (muter_id <> muted_id)
+);
+
+-- Author blocked the user: the user can't see or interact with chirps of the author
+-- +goose StatementBegin
+CREATE FUNCTION is_blocked(author_id UUID, user_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = author_id AND blocked_id = user_id)
+$$;
+-- +goose StatementEnd
+
+-- Chirps of the author are filtered from listings of the viewer: block in any direction or mute
+-- +goose StatementBegin
+CREATE FUNCTION is_hidden(viewer_id UUID, author_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocker_id = author_id AND blocked_id = viewer_id)
+           OR (blocker_id = viewer_id AND blocked_id = author_id)
+    ) OR EXISTS (
+        SELECT 1 FROM mutes WHERE muter_id = viewer_id AND muted_id = author_id
+    )
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS is_hidden(UUID, UUID);
+DROP FUNCTION IF EXISTS is_blocked(UUID, UUID);
+DROP TABLE IF EXISTS mutes;
+DROP TABLE IF EXISTS blocks;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'unlisted', 'followers', 'mentioned'));
+
+-- Reader can see the chirp: public and unlisted chirps are readable by everyone,
+-- followers-only by followers and mentioned-only by mentioned users. Author and mentioned users can read every chirp
+-- +goose StatementBegin
+CREATE FUNCTION can_read(reader UUID, author UUID, chirp UUID, level TEXT) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT level IN ('public', 'unlisted')
+        OR reader = author
+        OR (level = 'followers' AND EXISTS (
+            SELECT 1 FROM follows WHERE follower_id = reader AND followee_id = author
+        ))
+        OR EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_id = chirp AND user_id = reader)
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS can_read(UUID, UUID, UUID, TEXT);
+ALTER TABLE chirps DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    actor_id UUID NOT NULL,
+    chirp_id UUID,
+    created_at TIMESTAMP NOT NULL,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_created_at_idx ON notifications (user_id, created_at DESC, id DESC);
+
+-- Repeated follow or reaction doesn't notify again
+CREATE UNIQUE INDEX notifications_unique_idx
+    ON notifications (user_id, type, actor_id, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'));
+
+-- Types without a row are enabled
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
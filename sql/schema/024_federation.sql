@@ -0,0 +1,82 @@
+-- +goose Up
+-- Key pair of local actor, created when the actor is first needed
+CREATE TABLE actor_keys (
+    user_id UUID PRIMARY KEY,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Actors of other servers, refreshed when they are fetched again
+CREATE TABLE remote_actors (
+    id UUID PRIMARY KEY,
+    uri TEXT NOT NULL UNIQUE,
+    key_id TEXT NOT NULL,
+    inbox TEXT NOT NULL,
+    preferred_username TEXT NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    fetched_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX remote_actors_key_id_idx ON remote_actors (key_id);
+
+-- Remote actors who follow local users
+CREATE TABLE remote_followers (
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    follow_uri TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, actor_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE
+);
+
+-- Remote actors followed by local users, accepted when the remote server sends Accept
+CREATE TABLE remote_following (
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    follow_uri TEXT NOT NULL UNIQUE,
+    accepted BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, actor_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE
+);
+
+CREATE INDEX remote_following_actor_id_idx ON remote_following (actor_id);
+
+-- Notes of followed remote actors and remote replies to local chirps, content is plain text
+CREATE TABLE remote_notes (
+    id UUID PRIMARY KEY,
+    uri TEXT NOT NULL UNIQUE,
+    actor_id UUID NOT NULL,
+    content TEXT NOT NULL,
+    url TEXT NOT NULL,
+    in_reply_to UUID,
+    published_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE,
+    FOREIGN KEY (in_reply_to) REFERENCES chirps(id) ON DELETE SET NULL
+);
+
+CREATE INDEX remote_notes_actor_published_idx ON remote_notes (actor_id, published_at DESC, id DESC);
+
+-- Likes of local chirps by remote actors, they are counted as `like` reactions
+CREATE TABLE remote_likes (
+    chirp_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    like_uri TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, actor_id),
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS remote_likes;
+DROP TABLE IF EXISTS remote_notes;
+DROP TABLE IF EXISTS remote_following;
+DROP TABLE IF EXISTS remote_followers;
+DROP TABLE IF EXISTS remote_actors;
+DROP TABLE IF EXISTS actor_keys;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_id UUID NULL REFERENCES media(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN avatar_id,
+DROP COLUMN bio,
+DROP COLUMN display_name;
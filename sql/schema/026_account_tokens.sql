@@ -0,0 +1,39 @@
+-- +goose Up
+-- Registration accepted any email, so the same address may be used by several accounts with other case.
+-- Merging accounts can't be done automatically, the migration stops and lists the emails to resolve
+-- +goose StatementBegin
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(email, ', ' ORDER BY email) INTO duplicates
+    FROM (SELECT lower(email) AS email FROM users GROUP BY lower(email) HAVING count(*) > 1) AS d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users with the same email in other case must be merged or renamed before the migration: %', duplicates;
+    END IF;
+END;
+$$;
+-- +goose StatementEnd
+
+CREATE UNIQUE INDEX users_email_idx ON users (lower(email));
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;
+
+-- Single-use tokens sent by email, only sha256 of the token is stored
+CREATE TABLE account_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    purpose TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+    email VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX account_tokens_user_id_idx ON account_tokens (user_id, purpose);
+
+-- +goose Down
+DROP TABLE IF EXISTS account_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
+DROP INDEX IF EXISTS users_email_idx;
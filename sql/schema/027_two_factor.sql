@@ -0,0 +1,26 @@
+-- +goose Up
+-- TOTP secret of the user, 2FA is on when enrollment is verified.
+-- last_counter is time step of the last accepted code, so a code works once
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP NULL,
+    last_counter BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Single-use recovery codes, only sha256 of the code is stored
+CREATE TABLE recovery_codes (
+    code_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Deleted rechirp stays in trash, so the chirp can be rechirped again. Restore of the deleted one is refused then
+DROP INDEX IF EXISTS chirps_user_id_rechirp_of_idx;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_user_id_rechirp_of_idx;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Login waiting for the second factor, id is in the MFA token. Challenge works once and allows
+-- a few attempts; attempts of challenges of the user limit guessing of codes with many logins
+CREATE TABLE mfa_challenges (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS mfa_challenges;
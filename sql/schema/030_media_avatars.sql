@@ -0,0 +1,13 @@
+-- +goose Up
+-- Avatar can't be attached to a chirp, purge of the chirp would delete it
+ALTER TABLE media ADD COLUMN is_avatar BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE media SET is_avatar = true
+WHERE chirp_id IS NULL AND id IN (SELECT avatar_id FROM users WHERE avatar_id IS NOT NULL);
+
+-- Uploads which were never attached are found by purger
+CREATE INDEX media_unattached_idx ON media (created_at) WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS media_unattached_idx;
+ALTER TABLE media DROP COLUMN IF EXISTS is_avatar;